@@ -0,0 +1,249 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+)
+
+// listenAdmin opens the admin api listener. Addresses that are absolute
+// paths are unix sockets, anything else is a tcp host:port. tcp listeners
+// are refused without a bearer token since anyone that can reach them
+// could reconfigure the server.
+func listenAdmin(c *conf.Admin) (net.Listener, error) {
+	if filepath.IsAbs(c.ListenAddr) {
+		// Remove a socket left behind by a previous run, but never
+		// anything else the path might have been pointed at.
+		if fi, err := os.Lstat(c.ListenAddr); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			os.Remove(c.ListenAddr)
+		}
+		l, err := net.Listen("unix", c.ListenAddr)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(c.ListenAddr, 0600); err != nil {
+			l.Close()
+			return nil, err
+		}
+		return l, nil
+	}
+
+	if c.BearerToken == "" {
+		return nil, errors.New("bearer_token is required when admin listen_addr is not a unix socket")
+	}
+	return net.Listen("tcp", c.ListenAddr)
+}
+
+func (s *server) serveAdmin(l net.Listener, token string) error {
+	return http.Serve(l, s.adminHandler(token))
+}
+
+func (s *server) adminHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/backends", s.adminBackends)
+	mux.HandleFunc("/backends/enable", s.adminSetBackendEnabled(true))
+	mux.HandleFunc("/backends/disable", s.adminSetBackendEnabled(false))
+	mux.HandleFunc("/mode", s.adminMode)
+	mux.HandleFunc("/cache/flush", s.adminFlushCache)
+	mux.HandleFunc("/overrides", s.adminOverrides)
+	mux.HandleFunc("/log_queries", s.adminLogQueries)
+
+	if token == "" {
+		return mux
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			adminError(w, http.StatusUnauthorized, "invalid bearer token")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+type adminBackend struct {
+	Name     string `json:"name"`
+	Mode     string `json:"mode"`
+	Addr     string `json:"addr"`
+	Disabled bool   `json:"disabled"`
+	backendStatsSnapshot
+}
+
+func (s *server) adminBackends(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet) {
+		return
+	}
+
+	backends := make([]adminBackend, 0, len(s.clients))
+	for _, c := range s.clients {
+		backends = append(backends, adminBackend{
+			Name:                 c.name,
+			Mode:                 c.mode.String(),
+			Addr:                 c.addr,
+			Disabled:             c.disabled.Load(),
+			backendStatsSnapshot: c.stats.snapshot(),
+		})
+	}
+	adminJSON(w, backends)
+}
+
+func (s *server) adminSetBackendEnabled(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !allowMethods(w, r, http.MethodPost) {
+			return
+		}
+
+		name := r.FormValue("name")
+		var found *client
+		for _, c := range s.clients {
+			if c.name == name {
+				found = c
+				break
+			}
+		}
+		if found == nil {
+			adminError(w, http.StatusNotFound, fmt.Sprintf("no backend named %q", name))
+			return
+		}
+
+		// Hold the lock from the count to the store so concurrent
+		// requests can't disable every backend between them.
+		s.backendsMu.Lock()
+		if !enabled && !found.disabled.Load() && len(s.enabledClients()) == 1 {
+			s.backendsMu.Unlock()
+			adminError(w, http.StatusConflict, "refusing to disable the last enabled backend")
+			return
+		}
+		found.disabled.Store(!enabled)
+		s.backendsMu.Unlock()
+
+		adminJSON(w, map[string]bool{"disabled": !enabled})
+	}
+}
+
+func (s *server) adminMode(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		v, ok := conf.Config_ResolveMode_value[r.FormValue("mode")]
+		if !ok {
+			adminError(w, http.StatusBadRequest, fmt.Sprintf("unknown resolve mode %q", r.FormValue("mode")))
+			return
+		}
+		s.mode.Store(v)
+	}
+
+	adminJSON(w, map[string]string{"mode": conf.Config_ResolveMode(s.mode.Load()).String()})
+}
+
+func (s *server) adminFlushCache(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodPost) {
+		return
+	}
+	if s.cache == nil {
+		adminError(w, http.StatusNotFound, "cache is not enabled")
+		return
+	}
+
+	var n int
+	if name := r.FormValue("name"); name != "" {
+		n = s.cache.flushName(name)
+	} else {
+		n = s.cache.flush()
+	}
+	adminJSON(w, map[string]int{"flushed": n})
+}
+
+func (s *server) adminOverrides(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost, http.MethodDelete) {
+		return
+	}
+
+	name := r.FormValue("name")
+	if name != "" {
+		name = dns.Fqdn(name)
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		ip := r.FormValue("ip")
+		if name == "" || net.ParseIP(ip) == nil {
+			adminError(w, http.StatusBadRequest, "name and a valid ip are required")
+			return
+		}
+		s.localOverrides.set(name, ip)
+		s.flushCachedName(name)
+	case http.MethodDelete:
+		if !s.localOverrides.remove(name) {
+			if s.localOverrides.inFile(name) {
+				adminError(w, http.StatusConflict, fmt.Sprintf("override for %q comes from the override file", name))
+				return
+			}
+			adminError(w, http.StatusNotFound, fmt.Sprintf("no runtime override for %q", name))
+			return
+		}
+		s.flushCachedName(name)
+	}
+
+	adminJSON(w, s.localOverrides.all())
+}
+
+func (s *server) flushCachedName(name string) {
+	if s.cache != nil {
+		s.cache.flushName(name)
+	}
+}
+
+func (s *server) adminLogQueries(w http.ResponseWriter, r *http.Request) {
+	if !allowMethods(w, r, http.MethodGet, http.MethodPost) {
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+		if err != nil {
+			adminError(w, http.StatusBadRequest, "enabled must be true or false")
+			return
+		}
+		s.logQueries.Store(enabled)
+	}
+
+	adminJSON(w, map[string]bool{"log_queries": s.logQueries.Load()})
+}
+
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	adminError(w, http.StatusMethodNotAllowed, "method not allowed")
+	return false
+}
+
+func adminJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func adminError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+)
+
+func TestAdminAPI(t *testing.T) {
+	backend := exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		return answerA(m, "192.0.2.1"), time.Millisecond, nil
+	})
+	s := newTestServer(conf.Config_InOrder, backend, backend)
+	ts := httptest.NewServer(s.adminHandler("secret"))
+	defer ts.Close()
+
+	do := func(method, path, token string) int {
+		req, err := http.NewRequest(method, ts.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := do("GET", "/backends", ""); code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated request got %d", code)
+	}
+	if code := do("GET", "/backends", "wrong"); code != http.StatusUnauthorized {
+		t.Errorf("bad token got %d", code)
+	}
+	bare, _ := http.NewRequest("GET", ts.URL+"/backends", nil)
+	bare.Header.Set("Authorization", "secret")
+	if resp, err := http.DefaultClient.Do(bare); err != nil {
+		t.Fatal(err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("token without Bearer prefix got %d", resp.StatusCode)
+	}
+
+	if code := do("POST", "/mode?mode=Concurrent", "secret"); code != http.StatusOK {
+		t.Errorf("set mode got %d", code)
+	}
+	if mode := conf.Config_ResolveMode(s.mode.Load()); mode != conf.Config_Concurrent {
+		t.Errorf("mode is %s", mode)
+	}
+
+	if code := do("POST", "/backends/disable?name=backend-0", "secret"); code != http.StatusOK {
+		t.Errorf("disable got %d", code)
+	}
+	if code := do("POST", "/backends/disable?name=backend-1", "secret"); code != http.StatusConflict {
+		t.Errorf("disabling last backend got %d", code)
+	}
+	if clients := s.enabledClients(); len(clients) != 1 || clients[0].name != "backend-1" {
+		t.Errorf("unexpected enabled clients: %v", clients)
+	}
+
+	if code := do("POST", "/overrides?name=vm1&ip=10.0.0.1", "secret"); code != http.StatusOK {
+		t.Errorf("add override got %d", code)
+	}
+	req := new(dns.Msg)
+	req.SetQuestion("vm1.", dns.TypeA)
+	w := &recorder{}
+	s.handleRequest(w, req)
+	if a, ok := w.msg.Answer[0].(*dns.A); !ok || a.A.String() != "10.0.0.1" {
+		t.Errorf("override not applied: %s", w.msg)
+	}
+
+	s.localOverrides.replace(map[string]string{"file1.": "10.0.0.9"})
+	if code := do("DELETE", "/overrides?name=file1", "secret"); code != http.StatusConflict {
+		t.Errorf("removing an override file entry got %d", code)
+	}
+	if code := do("DELETE", "/overrides?name=vm1", "secret"); code != http.StatusOK {
+		t.Errorf("remove override got %d", code)
+	}
+	if code := do("DELETE", "/overrides?name=vm1", "secret"); code != http.StatusNotFound {
+		t.Errorf("removing a missing override got %d", code)
+	}
+
+	if code := do("POST", "/log_queries?enabled=true", "secret"); code != http.StatusOK || !s.logQueries.Load() {
+		t.Errorf("enable log_queries got %d", code)
+	}
+}
+
+func TestAdminDisableConcurrent(t *testing.T) {
+	backend := exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		return answerA(m, "192.0.2.1"), time.Millisecond, nil
+	})
+	const n = 8
+	backends := make([]exchanger, n)
+	for i := range backends {
+		backends[i] = backend
+	}
+	s := newTestServer(conf.Config_InOrder, backends...)
+	h := s.adminHandler("")
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := httptest.NewRequest("POST", fmt.Sprintf("/backends/disable?name=backend-%d", i), nil)
+			h.ServeHTTP(httptest.NewRecorder(), req)
+		}(i)
+	}
+	wg.Wait()
+
+	if clients := s.enabledClients(); len(clients) != 1 {
+		t.Fatalf("%d backends left enabled, expected 1", len(clients))
+	}
+}
+
+func TestAdminListenKeepsFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "admin.sock")
+	if err := os.WriteFile(path, []byte("not a socket"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if l, err := listenAdmin(&conf.Admin{ListenAddr: path}); err == nil {
+		l.Close()
+		t.Fatal("expected listening over a regular file to fail")
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != "not a socket" {
+		t.Fatalf("regular file was modified: %q %v", b, err)
+	}
+
+	os.Remove(path)
+	l, err := listenAdmin(&conf.Admin{ListenAddr: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Simulate a stale socket left by a previous run.
+	if ul, ok := l.(*net.UnixListener); ok {
+		ul.SetUnlinkOnClose(false)
+	}
+	l.Close()
+	l, err = listenAdmin(&conf.Admin{ListenAddr: path})
+	if err != nil {
+		t.Fatalf("stale socket was not replaced: %s", err)
+	}
+	l.Close()
+}
+
+func TestDuplicateBackendNames(t *testing.T) {
+	config := &conf.Config{Servers: []conf.Server{
+		{Name: "dup", Type: conf.Server_UDP, HostPort: []string{"192.0.2.1:53"}},
+		{Name: "dup", Type: conf.Server_DOH, DohUrl: "https://192.0.2.2/dns-query", HostPort: []string{"192.0.2.2:443"}},
+	}}
+	if _, err := newClients(config); err == nil {
+		t.Fatal("expected duplicate backend names to be rejected")
+	}
+
+	config.Servers[1].Name = "doh"
+	clients, err := newClients(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clients[1].name != "doh" {
+		t.Errorf("doh backend is named %q", clients[1].name)
+	}
+}
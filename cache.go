@@ -0,0 +1,365 @@
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+)
+
+const (
+	defaultCacheEntries = 10000
+	maxCacheTTL         = 24 * time.Hour
+
+	// RFC 8767 recommends 30s for both the ttl of stale answers and how
+	// long to wait before retrying the backends after they failed.
+	defaultStaleTTL      = 30 * time.Second
+	staleRecheckInterval = 30 * time.Second
+	defaultMaxStale      = 24 * time.Hour
+	defaultClientTimeout = 1800 * time.Millisecond
+
+	defaultPrefetchPercent = 10
+)
+
+// cache is an LRU cache of upstream responses keyed by question.
+type cache struct {
+	maxEntries    int
+	serveStale    bool
+	staleTTL      time.Duration
+	maxStale      time.Duration
+	clientTimeout time.Duration
+
+	prefetchMinHits int
+	prefetchPercent int
+
+	persistFile string
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	lru     *list.List // front is most recently used
+}
+
+type cacheEntry struct {
+	key    string
+	msg    *dns.Msg
+	stored time.Time
+	ttl    time.Duration
+	elem   *list.Element
+
+	hits        int
+	prefetching bool
+
+	// refreshFailed is the last time the backends failed to refresh
+	// this entry while it was stale.
+	refreshFailed time.Time
+}
+
+func (e *cacheEntry) expires() time.Time {
+	return e.stored.Add(e.ttl)
+}
+
+func newCache(c *conf.Cache) *cache {
+	cc := &cache{
+		maxEntries:    int(c.MaxEntries),
+		serveStale:    c.ServeStale,
+		staleTTL:      time.Duration(c.StaleTtlSeconds) * time.Second,
+		maxStale:      time.Duration(c.MaxStaleSeconds) * time.Second,
+		clientTimeout: time.Duration(c.ClientTimeoutMs) * time.Millisecond,
+
+		prefetchMinHits: int(c.PrefetchMinHits),
+		prefetchPercent: int(c.PrefetchThresholdPercent),
+
+		persistFile: c.PersistFile,
+
+		entries: make(map[string]*cacheEntry),
+		lru:     list.New(),
+	}
+	if cc.maxEntries == 0 {
+		cc.maxEntries = defaultCacheEntries
+	}
+	if cc.staleTTL == 0 {
+		cc.staleTTL = defaultStaleTTL
+	}
+	if cc.maxStale == 0 {
+		cc.maxStale = defaultMaxStale
+	}
+	if cc.clientTimeout == 0 {
+		cc.clientTimeout = defaultClientTimeout
+	}
+	if cc.prefetchPercent == 0 {
+		cc.prefetchPercent = defaultPrefetchPercent
+	}
+	return cc
+}
+
+// cacheKey returns the key for the question in r. Only the parts of the
+// query that change the shape of the answer are part of the key. scope
+// is the client subnet the backends are sent, from ecsScope.
+func cacheKey(r *dns.Msg, scope string) string {
+	if len(r.Question) != 1 {
+		return ""
+	}
+	q := r.Question[0]
+	var edns, do bool
+	if opt := r.IsEdns0(); opt != nil {
+		edns = true
+		do = opt.Do()
+	}
+	key := fmt.Sprintf("%s/%d/%d/edns=%t/do=%t/cd=%t", strings.ToLower(q.Name), q.Qtype, q.Qclass, edns, do, r.CheckingDisabled)
+	if subnet := clientSubnet(r); subnet != nil {
+		// Backends that get the client's subnet passed through may
+		// answer differently for each one.
+		key += fmt.Sprintf("/ecs=%s/%d", subnet.Address, subnet.SourceNetmask)
+	}
+	if scope != "" {
+		key += "/scope=" + scope
+	}
+	return key
+}
+
+// get returns a response to r from the cache. If the entry has expired
+// but may still be served stale, fresh is false and resp is a stale
+// answer. resp is nil on a cache miss. prefetch is true when the caller
+// should refresh a popular entry that is about to expire; it is only
+// returned once per entry.
+func (c *cache) get(r *dns.Msg, scope string) (resp *dns.Msg, fresh, prefetch bool) {
+	key := cacheKey(r, scope)
+	if key == "" {
+		return nil, false, false
+	}
+
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := c.entries[key]
+	if e == nil {
+		return nil, false, false
+	}
+
+	if now.Before(e.expires()) {
+		c.lru.MoveToFront(e.elem)
+		e.hits++
+		if c.shouldPrefetch(e, now) {
+			e.prefetching = true
+			prefetch = true
+		}
+		return e.response(r, now), true, prefetch
+	}
+
+	if !c.serveStale || now.After(e.expires().Add(c.maxStale)) {
+		c.remove(e)
+		return nil, false, false
+	}
+
+	c.lru.MoveToFront(e.elem)
+	return c.staleResponse(e, r), false, false
+}
+
+// shouldPrefetch reports whether e has been hit often enough and is
+// close enough to expiring that it should be refreshed now.
+func (c *cache) shouldPrefetch(e *cacheEntry, now time.Time) bool {
+	if c.prefetchMinHits == 0 || e.prefetching || e.hits < c.prefetchMinHits {
+		return false
+	}
+	remaining := e.expires().Sub(now)
+	return remaining*100 <= e.ttl*time.Duration(c.prefetchPercent)
+}
+
+// recentlyFailed reports whether the backends failed to refresh the stale
+// entry for r within the last staleRecheckInterval. While this is true
+// stale answers are returned immediately instead of waiting on the
+// backends.
+func (c *cache) recentlyFailed(r *dns.Msg, scope string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[cacheKey(r, scope)]
+	return e != nil && time.Since(e.refreshFailed) < staleRecheckInterval
+}
+
+func (c *cache) markFailed(r *dns.Msg, scope string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e := c.entries[cacheKey(r, scope)]; e != nil {
+		e.refreshFailed = time.Now()
+	}
+}
+
+// prefetchDone allows the entry for r to be prefetched again. A
+// successful prefetch replaces the entry, but one that failed or got an
+// uncacheable answer leaves it in place.
+func (c *cache) prefetchDone(r *dns.Msg, scope string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e := c.entries[cacheKey(r, scope)]; e != nil {
+		e.prefetching = false
+	}
+}
+
+// put stores resp as the answer to r if it is cacheable.
+func (c *cache) put(r, resp *dns.Msg, scope string) {
+	key := cacheKey(r, scope)
+	if key == "" {
+		return
+	}
+	ttl, ok := responseTTL(resp)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e := c.entries[key]; e != nil {
+		c.remove(e)
+	}
+
+	e := &cacheEntry{
+		key:    key,
+		msg:    resp.Copy(),
+		stored: time.Now(),
+		ttl:    ttl,
+	}
+	e.elem = c.lru.PushFront(e)
+	c.entries[key] = e
+
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back().Value.(*cacheEntry))
+	}
+}
+
+// flush removes every entry from the cache.
+func (c *cache) flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.entries)
+	c.entries = make(map[string]*cacheEntry)
+	c.lru.Init()
+	return n
+}
+
+// flushName removes the entries for every query type of name.
+func (c *cache) flushName(name string) int {
+	prefix := strings.ToLower(dns.Fqdn(name)) + "/"
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var n int
+	for key, e := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(e)
+			n++
+		}
+	}
+	return n
+}
+
+func (c *cache) remove(e *cacheEntry) {
+	c.lru.Remove(e.elem)
+	delete(c.entries, e.key)
+}
+
+// response returns a copy of the cached message as an answer to r with
+// ttls reduced by the time spent in the cache.
+func (e *cacheEntry) response(r *dns.Msg, now time.Time) *dns.Msg {
+	age := uint32(now.Sub(e.stored) / time.Second)
+	resp := e.msg.Copy()
+	resp.Id = r.Id
+	resp.Question = r.Question
+	forEachRR(resp, func(rr dns.RR) {
+		hdr := rr.Header()
+		if hdr.Ttl > age {
+			hdr.Ttl -= age
+		} else {
+			hdr.Ttl = 0
+		}
+	})
+	return resp
+}
+
+// staleResponse returns the expired entry e as an RFC 8767 stale answer
+// to r: every ttl is set to staleTTL and, for EDNS clients, an extended
+// DNS error "Stale Answer" is attached.
+func (c *cache) staleResponse(e *cacheEntry, r *dns.Msg) *dns.Msg {
+	resp := e.msg.Copy()
+	resp.Id = r.Id
+	resp.Question = r.Question
+	ttl := uint32(c.staleTTL / time.Second)
+	forEachRR(resp, func(rr dns.RR) {
+		rr.Header().Ttl = ttl
+	})
+	if reqOpt := r.IsEdns0(); reqOpt != nil && resp.IsEdns0() == nil {
+		resp.SetEdns0(reqOpt.UDPSize(), reqOpt.Do())
+	}
+	if opt := resp.IsEdns0(); opt != nil {
+		opt.Option = append(opt.Option, &dns.EDNS0_EDE{
+			InfoCode: dns.ExtendedErrorCodeStaleAnswer,
+		})
+	}
+	return resp
+}
+
+// forEachRR calls fn for every resource record in m except the OPT
+// pseudo record.
+func forEachRR(m *dns.Msg, fn func(dns.RR)) {
+	for _, section := range [][]dns.RR{m.Answer, m.Ns, m.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype == dns.TypeOPT {
+				continue
+			}
+			fn(rr)
+		}
+	}
+}
+
+// responseTTL returns how long resp may be cached. Negative answers are
+// cached for the SOA minimum as described in RFC 2308.
+func responseTTL(resp *dns.Msg) (time.Duration, bool) {
+	if resp.Truncated {
+		return 0, false
+	}
+	if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+		return 0, false
+	}
+
+	var (
+		ttl   uint32
+		found bool
+	)
+	minTTL := func(t uint32) {
+		if !found || t < ttl {
+			ttl = t
+		}
+		found = true
+	}
+
+	if len(resp.Answer) == 0 {
+		for _, rr := range resp.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				minTTL(soa.Hdr.Ttl)
+				minTTL(soa.Minttl)
+			}
+		}
+		if !found {
+			return 0, false
+		}
+	} else {
+		forEachRR(resp, func(rr dns.RR) {
+			minTTL(rr.Header().Ttl)
+		})
+	}
+
+	d := time.Duration(ttl) * time.Second
+	if d <= 0 {
+		return 0, false
+	}
+	if d > maxCacheTTL {
+		d = maxCacheTTL
+	}
+	return d, true
+}
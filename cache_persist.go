@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const cacheSnapshotVersion = 1
+
+// cacheSnapshot is the on disk format of the cache. Messages are stored
+// in wire format along with the time they were originally cached so
+// their remaining ttl can be computed when they are loaded.
+type cacheSnapshot struct {
+	Version int
+	Entries []cacheSnapshotEntry // least recently used first
+}
+
+type cacheSnapshotEntry struct {
+	Key    string
+	Stored time.Time
+	Msg    []byte
+}
+
+// save writes the cache to path. The file is replaced atomically so a
+// crash during save leaves the previous snapshot intact.
+func (c *cache) save(path string) error {
+	snap := cacheSnapshot{
+		Version: cacheSnapshotVersion,
+	}
+
+	c.mu.Lock()
+	for el := c.lru.Back(); el != nil; el = el.Prev() {
+		e := el.Value.(*cacheEntry)
+		b, err := e.msg.Pack()
+		if err != nil {
+			continue
+		}
+		snap.Entries = append(snap.Entries, cacheSnapshotEntry{
+			Key:    e.key,
+			Stored: e.stored,
+			Msg:    b,
+		})
+	}
+	c.mu.Unlock()
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	w := bufio.NewWriter(f)
+	if err := gob.NewEncoder(w).Encode(snap); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// load adds the entries saved in path to the cache, skipping entries
+// that can no longer be served. It returns the number of entries loaded.
+func (c *cache) load(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var snap cacheSnapshot
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&snap); err != nil {
+		return 0, fmt.Errorf("decode cache snapshot: %w", err)
+	}
+	if snap.Version != cacheSnapshotVersion {
+		return 0, fmt.Errorf("unsupported cache snapshot version %d", snap.Version)
+	}
+
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var loaded int
+	for _, se := range snap.Entries {
+		m := new(dns.Msg)
+		if err := m.Unpack(se.Msg); err != nil {
+			continue
+		}
+		ttl, ok := responseTTL(m)
+		if !ok {
+			continue
+		}
+
+		expires := se.Stored.Add(ttl)
+		if c.serveStale {
+			expires = expires.Add(c.maxStale)
+		}
+		if now.After(expires) {
+			continue
+		}
+
+		if e := c.entries[se.Key]; e != nil {
+			c.remove(e)
+		}
+		e := &cacheEntry{
+			key:    se.Key,
+			msg:    m,
+			stored: se.Stored,
+			ttl:    ttl,
+		}
+		e.elem = c.lru.PushFront(e)
+		c.entries[se.Key] = e
+		loaded++
+	}
+
+	for c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back().Value.(*cacheEntry))
+	}
+
+	return loaded, nil
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+)
+
+type benchQuery struct {
+	name  string
+	qtype uint16
+}
+
+type benchSample struct {
+	d    time.Duration
+	cold bool
+	err  bool
+}
+
+type benchResult struct {
+	Backend   string           `json:"backend"`
+	Mode      string           `json:"mode"`
+	Addr      string           `json:"addr"`
+	Queries   int              `json:"queries"`
+	Errors    int              `json:"errors"`
+	ErrorRate float64          `json:"error_rate"`
+	All       benchPercentiles `json:"all"`
+	Cold      benchPercentiles `json:"cold"`
+	Warm      benchPercentiles `json:"warm"`
+}
+
+type benchPercentiles struct {
+	Count int   `json:"count"`
+	P50US int64 `json:"p50_us"`
+	P90US int64 `json:"p90_us"`
+	P99US int64 `json:"p99_us"`
+}
+
+// benchCmd measures the latency of every configured backend by sending
+// each of them the same list of queries.
+func benchCmd(args []string) {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	confPath := fs.String("conf", "dnsforward.conf", "Path to config file")
+	namesFile := fs.String("names", "", "File of names to query, one per line with an optional type")
+	queryLog := fs.String("querylog", "", "Replay the names requested in a JSON query log")
+	count := fs.Int("n", 0, "Queries per backend (defaults to two passes over the names)")
+	concurrency := fs.Int("c", 10, "Concurrent queries per backend")
+	qtypeStr := fs.String("type", "A", "Query type for names without one")
+	timeout := fs.Duration("timeout", 5*time.Second, "Per query timeout")
+	format := fs.String("format", "table", "Output format: table or json")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s bench [flags] (-names file | -querylog file)\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	defaultType, ok := dns.StringToType[strings.ToUpper(*qtypeStr)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown query type %q\n", *qtypeStr)
+		os.Exit(2)
+	}
+	if *format != "table" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		os.Exit(2)
+	}
+	if *concurrency < 1 {
+		*concurrency = 1
+	}
+
+	var (
+		queries []benchQuery
+		err     error
+	)
+	switch {
+	case *namesFile != "":
+		queries, err = readBenchNames(*namesFile, defaultType)
+	case *queryLog != "":
+		queries, err = readBenchQueryLog(*queryLog)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	queries = dedupeBenchQueries(queries)
+	if len(queries) == 0 {
+		fmt.Fprintln(os.Stderr, "no queries to send")
+		os.Exit(1)
+	}
+
+	n := *count
+	if n == 0 {
+		n = 2 * len(queries)
+	}
+
+	config, err := conf.Load(*confPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	clients, err := newClients(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	results := make([]benchResult, 0, len(clients))
+	for _, c := range clients {
+		samples := benchBackend(c, queries, n, *concurrency, *timeout)
+		results = append(results, summarizeBench(c, samples))
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(results)
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "BACKEND\tQUERIES\tERR%\tP50\tP90\tP99\tCOLD P50\tCOLD P99\tWARM P50\tWARM P99\t")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+			r.Backend, r.Queries, 100*r.ErrorRate,
+			usDuration(r.All.P50US), usDuration(r.All.P90US), usDuration(r.All.P99US),
+			usDuration(r.Cold.P50US), usDuration(r.Cold.P99US),
+			usDuration(r.Warm.P50US), usDuration(r.Warm.P99US))
+	}
+	tw.Flush()
+}
+
+// benchBackend sends n queries to c, cycling through queries, which must
+// be distinct. A cold pass sends each query once and finishes before a
+// warm pass sends the repeats, so warm queries can always be answered
+// from the backend's cache.
+func benchBackend(c *client, queries []benchQuery, n, concurrency int, timeout time.Duration) []benchSample {
+	cold := queries
+	if n < len(cold) {
+		cold = cold[:n]
+	}
+	var warm []benchQuery
+	for i := len(cold); i < n; i++ {
+		warm = append(warm, queries[i%len(queries)])
+	}
+
+	samples := runBenchPass(c, cold, true, concurrency, timeout)
+	return append(samples, runBenchPass(c, warm, false, concurrency, timeout)...)
+}
+
+// runBenchPass sends queries to c and returns a sample for each, labeled
+// with cold.
+func runBenchPass(c *client, queries []benchQuery, cold bool, concurrency int, timeout time.Duration) []benchSample {
+	samples := make([]benchSample, len(queries))
+	work := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				q := queries[i]
+				m := new(dns.Msg)
+				m.SetQuestion(dns.Fqdn(q.name), q.qtype)
+
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				result := queryOnce(ctx, c, m)
+				cancel()
+
+				samples[i] = benchSample{
+					d:    result.queryTime,
+					cold: cold,
+					err:  result.err != nil || result.r.Rcode == dns.RcodeServerFailure,
+				}
+			}
+		}()
+	}
+
+	for i := range queries {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	return samples
+}
+
+// dedupeBenchQueries returns queries without repeats, in the order each
+// was first seen. Query logs repeat popular names, and a repeat would be
+// a cache hit rather than a cold query.
+func dedupeBenchQueries(queries []benchQuery) []benchQuery {
+	seen := make(map[benchQuery]bool)
+	var out []benchQuery
+	for _, q := range queries {
+		key := benchQuery{name: strings.ToLower(dns.Fqdn(q.name)), qtype: q.qtype}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, q)
+	}
+	return out
+}
+
+func summarizeBench(c *client, samples []benchSample) benchResult {
+	var all, cold, warm []time.Duration
+	r := benchResult{
+		Backend: c.name,
+		Mode:    c.mode.String(),
+		Addr:    c.addr,
+		Queries: len(samples),
+	}
+	for _, s := range samples {
+		if s.err {
+			r.Errors++
+			continue
+		}
+		all = append(all, s.d)
+		if s.cold {
+			cold = append(cold, s.d)
+		} else {
+			warm = append(warm, s.d)
+		}
+	}
+	if r.Queries > 0 {
+		r.ErrorRate = float64(r.Errors) / float64(r.Queries)
+	}
+	r.All = benchPercentilesOf(all)
+	r.Cold = benchPercentilesOf(cold)
+	r.Warm = benchPercentilesOf(warm)
+	return r
+}
+
+func benchPercentilesOf(d []time.Duration) benchPercentiles {
+	sortDurations(d)
+	return benchPercentiles{
+		Count: len(d),
+		P50US: percentile(d, 50).Microseconds(),
+		P90US: percentile(d, 90).Microseconds(),
+		P99US: percentile(d, 99).Microseconds(),
+	}
+}
+
+func usDuration(us int64) string {
+	if us == 0 {
+		return "-"
+	}
+	return (time.Duration(us) * time.Microsecond).Round(10 * time.Microsecond).String()
+}
+
+// readBenchNames reads a list of names, one per line, each optionally
+// followed by a query type. Blank lines and # comments are ignored.
+func readBenchNames(path string, defaultType uint16) ([]benchQuery, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var queries []benchQuery
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		q := benchQuery{name: fields[0], qtype: defaultType}
+		if len(fields) > 1 {
+			t, ok := dns.StringToType[strings.ToUpper(fields[1])]
+			if !ok {
+				return nil, fmt.Errorf("%s:%d: unknown query type %q", path, lineNo, fields[1])
+			}
+			q.qtype = t
+		}
+		queries = append(queries, q)
+	}
+	return queries, scanner.Err()
+}
+
+// readBenchQueryLog extracts the questions of every request event in a
+// JSON query log, in the order they were received.
+func readBenchQueryLog(path string) ([]benchQuery, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var queries []benchQuery
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var evt struct {
+			Evt   string `json:"evt"`
+			Req   string `json:"req"`
+			QName string `json:"qname"`
+			QType string `json:"qtype"`
+		}
+		err := dec.Decode(&evt)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if evt.Evt != "request" {
+			continue
+		}
+		if t, ok := dns.StringToType[evt.QType]; ok && evt.QName != "" {
+			queries = append(queries, benchQuery{name: evt.QName, qtype: t})
+			continue
+		}
+		queries = append(queries, parseReqQuestions(evt.Req)...)
+	}
+	return queries, nil
+}
+
+var reqQuestionRe = regexp.MustCompile(`q=([^/ ]+)/([^/ ]+)/([^ }]+)`)
+
+// parseReqQuestions parses the questions out of a message formatted by
+// msg.String.
+func parseReqQuestions(req string) []benchQuery {
+	var queries []benchQuery
+	for _, m := range reqQuestionRe.FindAllStringSubmatch(req, -1) {
+		t, ok := dns.StringToType[m[3]]
+		if !ok {
+			continue
+		}
+		queries = append(queries, benchQuery{name: m[1], qtype: t})
+	}
+	return queries
+}
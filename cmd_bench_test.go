@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestBenchColdWarm(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu    sync.Mutex
+		seen  = make(map[string]int)
+		early []string // names repeated before every name was seen once
+	)
+	queries := dedupeBenchQueries([]benchQuery{
+		{name: "a.example", qtype: dns.TypeA},
+		{name: "b.example", qtype: dns.TypeA},
+		{name: "A.example.", qtype: dns.TypeA},
+		{name: "a.example", qtype: dns.TypeAAAA},
+		{name: "c.example", qtype: dns.TypeA},
+		{name: "b.example", qtype: dns.TypeA},
+	})
+	if len(queries) != 4 {
+		t.Fatalf("expected 4 distinct queries, got %v", queries)
+	}
+
+	srv := &dns.Server{
+		PacketConn: pc,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, m *dns.Msg) {
+			q := m.Question[0]
+			key := q.Name + "/" + dns.TypeToString[q.Qtype]
+			mu.Lock()
+			seen[key]++
+			if seen[key] > 1 && len(seen) < len(queries) {
+				early = append(early, key)
+			}
+			mu.Unlock()
+
+			resp := new(dns.Msg)
+			resp.SetReply(m)
+			w.WriteMsg(resp)
+		}),
+	}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+
+	c := newClassicClient("bench", pc.LocalAddr().String(), nil)
+	samples := benchBackend(c, queries, 10, 3, time.Second)
+	r := summarizeBench(c, samples)
+
+	if r.Queries != 10 || r.Errors != 0 {
+		t.Fatalf("expected 10 queries without errors, got %+v", r)
+	}
+	if r.Cold.Count != 4 || r.Warm.Count != 6 {
+		t.Fatalf("expected 4 cold and 6 warm samples, got %d and %d", r.Cold.Count, r.Warm.Count)
+	}
+
+	mu.Lock()
+	if len(early) > 0 {
+		t.Fatalf("warm queries sent before the cold pass finished: %v", early)
+	}
+	for key, n := range seen {
+		if n < 2 {
+			t.Errorf("%s: expected a cold and a warm query, got %d", key, n)
+		}
+	}
+	mu.Unlock()
+
+	samples = benchBackend(c, queries, 2, 3, time.Second)
+	if r := summarizeBench(c, samples); r.Cold.Count != 2 || r.Warm.Count != 0 {
+		t.Fatalf("expected 2 cold samples, got %+v", r)
+	}
+}
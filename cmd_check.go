@@ -0,0 +1,253 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+)
+
+// checkCmd validates a config file and the files it references without
+// starting the server.
+func checkCmd(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	confPath := fs.String("conf", "dnsforward.conf", "Path to config file")
+	fs.Parse(args)
+
+	config, err := conf.Load(*confPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", *confPath, err)
+		os.Exit(1)
+	}
+
+	problems := checkConfig(config)
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", *confPath, p)
+	}
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+
+	fmt.Printf("%s: ok\n", *confPath)
+}
+
+// checkConfig returns a description of every problem found in config.
+func checkConfig(config *conf.Config) []string {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if config.ListenAddr != "" && config.ListenAddr != "SOCKET_ACTIVATION" {
+		if err := checkHostPort(config.ListenAddr, false); err != nil {
+			addf("listen_addr %q: %s", config.ListenAddr, err)
+		}
+	}
+
+	if len(config.Servers) == 0 {
+		addf("no servers configured")
+	}
+
+	for _, b := range config.BootstrapServers {
+		if err := checkHostPort(b, true); err != nil {
+			addf("bootstrap_servers %q: %s", b, err)
+		}
+	}
+
+	names := make(map[string]int)
+	for i, s := range config.Servers {
+		label := fmt.Sprintf("server %d (%q)", i+1, s.Name)
+		reported := len(problems)
+
+		// Like newClients, allow one server without a name.
+		if prev, ok := names[s.Name]; ok {
+			addf("%s: duplicate name, also used by server %d", label, prev)
+		} else {
+			names[s.Name] = i + 1
+		}
+
+		if len(s.HostPort) == 0 {
+			if s.Type != conf.Server_DOH {
+				addf("%s: host_port is required", label)
+			} else if len(config.BootstrapServers) == 0 && s.Proxy == "" {
+				addf("%s: host_port is required unless bootstrap_servers or proxy is set", label)
+			}
+		} else if len(s.HostPort) > 1 && s.Type != conf.Server_DOH {
+			addf("%s: only DOH servers can have more than one host_port", label)
+		}
+		for _, hp := range s.HostPort {
+			if err := checkHostPort(hp, true); err != nil {
+				addf("%s: host_port %q: %s", label, hp, err)
+			}
+		}
+
+		if s.Ecs != nil {
+			if _, err := newECSPolicy(s.Ecs); err != nil {
+				addf("%s: ecs: %s", label, err)
+			}
+		}
+
+		switch s.Type {
+		case conf.Server_UDP:
+			if s.DohUrl != "" {
+				addf("%s: doh_url is set on a UDP server", label)
+			}
+			if s.PaddingBlockSize != 0 || s.DisablePadding {
+				addf("%s: padding is only used for DOH servers", label)
+			}
+			if s.DohMethod != conf.Server_POST {
+				addf("%s: doh_method is only used for DOH servers", label)
+			}
+			if s.Http3 {
+				addf("%s: http3 is only used for DOH servers", label)
+			}
+			if len(s.SpkiPins) > 0 || s.CaFile != "" {
+				addf("%s: spki_pins and ca_file are only used for DOH servers", label)
+			}
+			if s.ClientCertFile != "" || s.ClientKeyFile != "" {
+				addf("%s: client certificates are only used for DOH servers", label)
+			}
+			if s.Proxy != "" {
+				if _, err := newProxyDialer(s.Proxy, s.Name); err != nil {
+					addf("%s: proxy: %s", label, err)
+				}
+			}
+		case conf.Server_DOH:
+			if s.DohUrl == "" {
+				addf("%s: doh_url is required for DOH servers", label)
+			} else if u, err := url.Parse(s.DohUrl); err != nil {
+				addf("%s: doh_url: %s", label, err)
+			} else if u.Scheme != "https" {
+				addf("%s: doh_url %q must use https", label, s.DohUrl)
+			} else if u.Hostname() == "" {
+				addf("%s: doh_url %q has no host", label, s.DohUrl)
+			}
+			if u, err := url.Parse(s.Proxy); err == nil && u.Scheme == "socks5" && len(s.HostPort) == 0 {
+				addf("%s: a socks5 proxy can't resolve the doh_url host; use socks5h:// or set host_port", label)
+			}
+			if s.Http3 && s.Proxy != "" {
+				addf("%s: http3 can't be used through a proxy", label)
+			}
+			if s.PaddingBlockSize > dns.MaxMsgSize {
+				addf("%s: padding_block_size %d is too large", label, s.PaddingBlockSize)
+			}
+			if opts, err := dohOptions(config, s); err != nil {
+				addf("%s: %s", label, err)
+			} else if len(problems) == reported {
+				// Catch problems only doh.New finds, like malformed pins.
+				if _, err := newDOHClient(s.Name, s.DohUrl, s.HostPort, opts...); err != nil {
+					addf("%s: %s", label, err)
+				}
+			}
+		default:
+			addf("%s: unknown type %d", label, s.Type)
+		}
+	}
+
+	if config.OverrideFile != "" {
+		err := readOverrides(config.OverrideFile, func(lineNo int, ip string, _ []string) {
+			if net.ParseIP(ip) == nil {
+				addf("%s:%d: invalid ip %q", config.OverrideFile, lineNo, ip)
+			}
+		})
+		if err != nil {
+			addf("override_file: %s", err)
+		}
+	}
+
+	if c := config.Cache; c != nil && c.PersistFile != "" {
+		if _, err := os.Stat(filepath.Dir(c.PersistFile)); err != nil {
+			addf("cache persist_file: %s", err)
+		}
+	}
+
+	if q := config.QueryLog; q != nil {
+		if q.Path != "" {
+			if _, err := os.Stat(filepath.Dir(q.Path)); err != nil {
+				addf("query_log path: %s", err)
+			}
+		}
+		if _, err := newLogFieldSet(q.Fields); err != nil {
+			addf("query_log fields: %s", err)
+		}
+	}
+
+	if d := config.Dnstap; d != nil {
+		switch {
+		case d.SocketPath == "" && d.FilePath == "":
+			addf("dnstap: socket_path or file_path is required")
+		case d.SocketPath != "" && d.FilePath != "":
+			addf("dnstap: only one of socket_path and file_path may be set")
+		case d.FilePath != "":
+			if _, err := os.Stat(filepath.Dir(d.FilePath)); err != nil {
+				addf("dnstap file_path: %s", err)
+			}
+		}
+	}
+
+	if d := config.Dnssec; d != nil {
+		if _, err := newValidator(d, nil); err != nil {
+			addf("dnssec: %s", err)
+		}
+	}
+
+	if config.Ecs != nil {
+		if _, err := newECSPolicy(config.Ecs); err != nil {
+			addf("ecs: %s", err)
+		}
+	}
+
+	if rp := config.RebindProtection; rp != nil {
+		if _, err := newRebindFilter(rp); err != nil {
+			addf("rebind_protection: %s", err)
+		}
+	}
+
+	if tr := config.Tracing; tr != nil {
+		if tr.Endpoint != "" {
+			if err := checkHostPort(tr.Endpoint, false); err != nil {
+				addf("tracing endpoint %q: %s", tr.Endpoint, err)
+			}
+		}
+		if tr.SamplePercent > 100 {
+			addf("tracing: sample_percent must be at most 100")
+		}
+	}
+
+	if a := config.Admin; a != nil {
+		if a.ListenAddr == "" {
+			addf("admin: listen_addr is required")
+		} else if !filepath.IsAbs(a.ListenAddr) {
+			if err := checkHostPort(a.ListenAddr, false); err != nil {
+				addf("admin listen_addr %q: %s", a.ListenAddr, err)
+			} else if a.BearerToken == "" {
+				addf("admin: bearer_token is required when listen_addr is not a unix socket")
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkHostPort verifies that hostPort is a host:port pair with a valid
+// port. If requireIP is set the host must be an ip address, as it is for
+// backends to avoid needing DNS to reach them.
+func checkHostPort(hostPort string, requireIP bool) error {
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return err
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	if requireIP && net.ParseIP(host) == nil {
+		return fmt.Errorf("host %q is not an ip address", host)
+	}
+	return nil
+}
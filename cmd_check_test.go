@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/psanford/dnsforward/conf"
+)
+
+func TestCheckConfig(t *testing.T) {
+	newConfig := func() *conf.Config {
+		return &conf.Config{
+			ListenAddr: "127.0.0.1:53",
+			Servers: []conf.Server{
+				{Name: "udp", Type: conf.Server_UDP, HostPort: []string{"192.0.2.1:53"}},
+				{Name: "doh", Type: conf.Server_DOH, DohUrl: "https://dns.example/dns-query", HostPort: []string{"192.0.2.2:443"}},
+			},
+		}
+	}
+
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing")
+	hosts := filepath.Join(dir, "hosts")
+	if err := os.WriteFile(hosts, []byte("10.0.0.1 vm1\n# comment\n10.0.0.300 vm2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		modify func(c *conf.Config)
+		want   string // substring of the only problem; "" for none
+	}{
+		{"valid", func(c *conf.Config) {}, ""},
+		{"no servers", func(c *conf.Config) { c.Servers = nil }, "no servers configured"},
+		{"bad listen_addr", func(c *conf.Config) { c.ListenAddr = "localhost" }, "listen_addr"},
+		{"bootstrap not an ip", func(c *conf.Config) { c.BootstrapServers = []string{"dns.example:53"} }, "is not an ip address"},
+		{"missing name", func(c *conf.Config) { c.Servers[0].Name = "" }, ""},
+		{"two missing names", func(c *conf.Config) {
+			c.Servers[0].Name = ""
+			c.Servers[1].Name = ""
+		}, "duplicate name"},
+		{"duplicate name", func(c *conf.Config) { c.Servers[1].Name = "udp" }, "duplicate name"},
+		{"udp missing host_port", func(c *conf.Config) { c.Servers[0].HostPort = nil }, "host_port is required"},
+		{"doh missing host_port", func(c *conf.Config) { c.Servers[1].HostPort = nil }, "unless bootstrap_servers or proxy is set"},
+		{"doh bootstrap", func(c *conf.Config) {
+			c.Servers[1].HostPort = nil
+			c.BootstrapServers = []string{"192.0.2.53:53"}
+		}, ""},
+		{"doh proxy without host_port", func(c *conf.Config) {
+			c.Servers[1].HostPort = nil
+			c.Servers[1].Proxy = "socks5h://127.0.0.1:9050"
+		}, ""},
+		{"doh socks5 without host_port", func(c *conf.Config) {
+			c.Servers[1].HostPort = nil
+			c.Servers[1].Proxy = "socks5://127.0.0.1:9050"
+		}, "use socks5h://"},
+		{"udp several host_ports", func(c *conf.Config) {
+			c.Servers[0].HostPort = append(c.Servers[0].HostPort, "192.0.2.3:53")
+		}, "only DOH servers can have more than one host_port"},
+		{"host_port not an ip", func(c *conf.Config) { c.Servers[1].HostPort = []string{"dns.example:443"} }, "is not an ip address"},
+		{"bad port", func(c *conf.Config) { c.Servers[0].HostPort = []string{"192.0.2.1:99999"} }, "invalid port"},
+		{"udp doh_url", func(c *conf.Config) { c.Servers[0].DohUrl = "https://dns.example/" }, "doh_url is set on a UDP server"},
+		{"udp padding", func(c *conf.Config) { c.Servers[0].DisablePadding = true }, "padding is only used for DOH servers"},
+		{"udp http3", func(c *conf.Config) { c.Servers[0].Http3 = true }, "http3 is only used for DOH servers"},
+		{"udp pins", func(c *conf.Config) { c.Servers[0].CaFile = "/etc/ssl/ca.pem" }, "spki_pins and ca_file are only used"},
+		{"udp client cert", func(c *conf.Config) { c.Servers[0].ClientCertFile = "/etc/ssl/client.pem" }, "client certificates are only used"},
+		{"udp bad proxy", func(c *conf.Config) { c.Servers[0].Proxy = "ftp://127.0.0.1:21" }, "proxy"},
+		{"doh missing url", func(c *conf.Config) { c.Servers[1].DohUrl = "" }, "doh_url is required"},
+		{"doh plain http", func(c *conf.Config) { c.Servers[1].DohUrl = "http://dns.example/dns-query" }, "must use https"},
+		{"doh url without host", func(c *conf.Config) { c.Servers[1].DohUrl = "https:///dns-query" }, "has no host"},
+		{"http3 with proxy", func(c *conf.Config) {
+			c.Servers[1].Http3 = true
+			c.Servers[1].Proxy = "socks5h://127.0.0.1:9050"
+		}, "http3 can't be used through a proxy"},
+		{"client cert without key", func(c *conf.Config) { c.Servers[1].ClientCertFile = missing }, "must be set together"},
+		{"client key without cert", func(c *conf.Config) { c.Servers[1].ClientKeyFile = missing }, "must be set together"},
+		{"missing client cert", func(c *conf.Config) {
+			c.Servers[1].ClientCertFile = missing
+			c.Servers[1].ClientKeyFile = missing
+		}, "no such file"},
+		{"missing ca_file", func(c *conf.Config) { c.Servers[1].CaFile = missing }, "no such file"},
+		{"padding too large", func(c *conf.Config) { c.Servers[1].PaddingBlockSize = 70000 }, "is too large"},
+		{"bad pin", func(c *conf.Config) { c.Servers[1].SpkiPins = []string{"not a pin"} }, "invalid SPKI pin"},
+		{"unknown type", func(c *conf.Config) { c.Servers[0].Type = 7 }, "unknown type"},
+		{"dnstap without path", func(c *conf.Config) { c.Dnstap = &conf.Dnstap{} }, "socket_path or file_path is required"},
+		{"dnstap both paths", func(c *conf.Config) {
+			c.Dnstap = &conf.Dnstap{SocketPath: "/run/dnstap.sock", FilePath: "/var/log/dnstap"}
+		}, "only one of socket_path and file_path"},
+		{"sample percent", func(c *conf.Config) { c.Tracing = &conf.Tracing{SamplePercent: 101} }, "sample_percent"},
+		{"admin without listen_addr", func(c *conf.Config) { c.Admin = &conf.Admin{} }, "listen_addr is required"},
+		{"admin tcp without token", func(c *conf.Config) {
+			c.Admin = &conf.Admin{ListenAddr: "127.0.0.1:8053"}
+		}, "bearer_token is required"},
+		{"override bad ip", func(c *conf.Config) { c.OverrideFile = hosts }, hosts + `:3: invalid ip "10.0.0.300"`},
+		{"missing override file", func(c *conf.Config) { c.OverrideFile = missing }, "override_file"},
+		{"query log dir", func(c *conf.Config) {
+			c.QueryLog = &conf.QueryLog{Path: filepath.Join(missing, "query.log")}
+		}, "query_log path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConfig()
+			tt.modify(c)
+			problems := checkConfig(c)
+			if tt.want == "" {
+				if len(problems) > 0 {
+					t.Fatalf("unexpected problems: %q", problems)
+				}
+				return
+			}
+			if len(problems) != 1 || !strings.Contains(problems[0], tt.want) {
+				t.Fatalf("expected one problem containing %q, got %q", tt.want, problems)
+			}
+		})
+	}
+}
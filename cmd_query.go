@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+)
+
+// queryCmd sends a single query to every configured backend, using the
+// same transports the server uses, and prints each backend's answer.
+func queryCmd(args []string) {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	confPath := fs.String("conf", "dnsforward.conf", "Path to config file")
+	timeout := fs.Duration("timeout", 5*time.Second, "Per backend query timeout")
+	clientAddr := fs.String("client", "", "Send the query as if from this client `ip`, for backends with an ecs policy")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s query [flags] name [type] [+dnssec]\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "       %s query [flags] type name [+dnssec]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	name, qtype, dnssec, err := parseQueryArgs(fs.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var client net.IP
+	if *clientAddr != "" {
+		client = net.ParseIP(*clientAddr)
+		if client == nil {
+			fmt.Fprintf(os.Stderr, "invalid -client ip %q\n", *clientAddr)
+			os.Exit(2)
+		}
+	}
+
+	config, err := conf.Load(*confPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	clients, err := newClients(config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	req := new(dns.Msg)
+	req.SetQuestion(dns.Fqdn(name), qtype)
+	if dnssec {
+		req.SetEdns0(4096, true)
+	}
+
+	results := make([]queryResult, len(clients))
+	var wg sync.WaitGroup
+	for i, c := range clients {
+		i, c := i, c
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+			defer cancel()
+			if client != nil {
+				ctx = withClientIP(ctx, client)
+			}
+			results[i] = queryOnce(ctx, c, req)
+		}()
+	}
+	wg.Wait()
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "BACKEND\tTRANSIT\tADDR\tRCODE\tRTT\tANSWER")
+	var ok int
+	for _, result := range results {
+		prefix := fmt.Sprintf("%s\t%s\t%s\t", result.name, result.mode, result.addr)
+		rtt := result.queryTime.Round(10 * time.Microsecond)
+		if result.err != nil {
+			fmt.Fprintf(tw, "%sERROR\t%s\t%s\n", prefix, rtt, result.err)
+			continue
+		}
+		ok++
+
+		rcode := dns.RcodeToString[result.r.Rcode]
+		if len(result.r.Answer) == 0 {
+			fmt.Fprintf(tw, "%s%s\t%s\t\n", prefix, rcode, rtt)
+			continue
+		}
+		for i, rr := range result.r.Answer {
+			if i > 0 {
+				prefix, rcode, rtt = "\t\t\t", "", 0
+			}
+			rttStr := ""
+			if rtt != 0 {
+				rttStr = rtt.String()
+			}
+			fmt.Fprintf(tw, "%s%s\t%s\t%s\n", prefix, rcode, rttStr, strings.ReplaceAll(rr.String(), "\t", " "))
+		}
+	}
+	tw.Flush()
+
+	if ok == 0 {
+		os.Exit(1)
+	}
+}
+
+// parseQueryArgs parses the name, optional type and +dnssec flag of a
+// query. Like dig, the type may come before or after the name.
+func parseQueryArgs(args []string) (name string, qtype uint16, dnssec bool, err error) {
+	var rest []string
+	for _, arg := range args {
+		if arg == "+dnssec" {
+			dnssec = true
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+
+	isType := func(s string) (uint16, bool) {
+		t, ok := dns.StringToType[strings.ToUpper(s)]
+		return t, ok
+	}
+	qtype = dns.TypeA
+	switch len(rest) {
+	case 0:
+		return "", 0, false, errors.New("no name to query")
+	case 1:
+		name = rest[0]
+	case 2:
+		if t, ok := isType(rest[1]); ok {
+			name, qtype = rest[0], t
+		} else if t, ok := isType(rest[0]); ok {
+			name, qtype = rest[1], t
+		} else {
+			return "", 0, false, fmt.Errorf("unknown query type %q", rest[1])
+		}
+	default:
+		return "", 0, false, fmt.Errorf("unexpected argument %q", rest[2])
+	}
+	return name, qtype, dnssec, nil
+}
+
+// queryOnce sends m to c without going through the server's logging or
+// health tracking. Like queryBackend it applies c's client subnet
+// policy, for the client recorded in ctx if any.
+func queryOnce(ctx context.Context, c *client, m *dns.Msg) queryResult {
+	ecs := c.ecs
+	if ecs == nil {
+		ecs = stripECS
+	}
+	q := ecs.query(m.Copy(), clientIP(ctx))
+
+	t0 := time.Now()
+	r, rtt, addr, err := c.exchange(ctx, q)
+	if err == nil {
+		ecs.response(m, r)
+	}
+	return queryResult{
+		r:         r,
+		rtt:       rtt,
+		err:       err,
+		queryTime: time.Since(t0),
+		name:      c.name,
+		mode:      c.mode,
+		addr:      addr,
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+)
+
+func TestParseQueryArgs(t *testing.T) {
+	tests := []struct {
+		args   []string
+		name   string
+		qtype  uint16
+		dnssec bool
+		err    bool
+	}{
+		{args: []string{"example.com"}, name: "example.com", qtype: dns.TypeA},
+		{args: []string{"example.com", "AAAA"}, name: "example.com", qtype: dns.TypeAAAA},
+		{args: []string{"mx", "example.com"}, name: "example.com", qtype: dns.TypeMX},
+		{args: []string{"+dnssec", "TXT", "example.com"}, name: "example.com", qtype: dns.TypeTXT, dnssec: true},
+		{args: []string{"example.com", "ds", "+dnssec"}, name: "example.com", qtype: dns.TypeDS, dnssec: true},
+		// A name that looks like a type is still a name when the type
+		// follows it.
+		{args: []string{"ns", "a"}, name: "ns", qtype: dns.TypeA},
+		{args: []string{"ns"}, name: "ns", qtype: dns.TypeA},
+		{args: []string{"example.com", "example.org"}, err: true},
+		{args: []string{"example.com", "A", "extra"}, err: true},
+		{args: []string{"+dnssec"}, err: true},
+		{args: nil, err: true},
+	}
+
+	for _, tt := range tests {
+		name, qtype, dnssec, err := parseQueryArgs(tt.args)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", tt.args, err)
+			continue
+		}
+		if name != tt.name || qtype != tt.qtype || dnssec != tt.dnssec {
+			t.Errorf("%q: got %s %s dnssec=%t, expected %s %s dnssec=%t", tt.args,
+				name, dns.TypeToString[qtype], dnssec, tt.name, dns.TypeToString[tt.qtype], tt.dnssec)
+		}
+	}
+}
+
+func TestQueryOnceECS(t *testing.T) {
+	var sent *dns.EDNS0_SUBNET
+	backend := exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		sent = clientSubnet(m)
+		resp := answerA(m, "192.0.2.1")
+		resp.SetEdns0(4096, false)
+		return resp, 0, nil
+	})
+	c := &client{
+		name:      "backend",
+		exchanger: backend,
+		ecs:       &ecsPolicy{mode: conf.Ecs_ADD, ipv4Prefix: 24, ipv6Prefix: 56},
+	}
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+
+	ctx := withClientIP(context.Background(), net.ParseIP("203.0.113.77"))
+	result := queryOnce(ctx, c, req)
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	if sent == nil || sent.Address.String() != "203.0.113.0" || sent.SourceNetmask != 24 {
+		t.Errorf("sent subnet %v, expected 203.0.113.0/24", sent)
+	}
+	if result.r.IsEdns0() != nil {
+		t.Error("OPT record returned for a query without EDNS")
+	}
+	if clientSubnet(req) != nil {
+		t.Error("query was modified")
+	}
+
+	queryOnce(context.Background(), c, req)
+	if sent != nil {
+		t.Errorf("sent subnet %v without a client address", sent)
+	}
+}
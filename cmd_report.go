@@ -0,0 +1,463 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// logEvent holds the union of the fields of the events written by
+// logJSON.
+type logEvent struct {
+	TS         time.Time       `json:"ts"`
+	Evt        string          `json:"evt"`
+	ID         string          `json:"id"`
+	DurationUS int64           `json:"duration_us"`
+	Backend    string          `json:"backend"`
+	Error      json.RawMessage `json:"error"`
+	Req        string          `json:"req"`
+	QName      string          `json:"qname"`
+	Prefetch   bool            `json:"prefetch"`
+}
+
+type backendReport struct {
+	Backend   string
+	Results   int
+	Errors    int
+	Latencies []time.Duration
+	Races     int
+	Wins      int
+	ErrorKind map[string]int
+}
+
+type nameReport struct {
+	Name      string
+	Latencies []time.Duration
+}
+
+type report struct {
+	backends map[string]*backendReport
+	names    map[string]*nameReport
+	requests int
+	failures int
+}
+
+// reportCmd summarizes the latency of each backend from JSON query logs.
+func reportCmd(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	sinceStr := fs.String("since", "", "Only include events after this time (RFC 3339 or a duration ago, e.g. 24h)")
+	untilStr := fs.String("until", "", "Only include events before this time (RFC 3339 or a duration ago)")
+	format := fs.String("format", "text", "Output format: text or csv")
+	table := fs.String("table", "backends", "Table to write in csv format: backends, errors or names")
+	top := fs.Int("top", 10, "Number of slowest names to show")
+	includePrefetch := fs.Bool("include-prefetch", false, "Include cache prefetch queries in latency stats")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s report [flags] [logfile...]\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	now := time.Now()
+	since, err := parseReportTime(*sinceStr, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -since: %s\n", err)
+		os.Exit(2)
+	}
+	until, err := parseReportTime(*untilStr, now)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -until: %s\n", err)
+		os.Exit(2)
+	}
+
+	rb := newReportBuilder()
+	add := func(e logEvent) {
+		if !since.IsZero() && e.TS.Before(since) {
+			return
+		}
+		if !until.IsZero() && e.TS.After(until) {
+			return
+		}
+		if e.Prefetch && !*includePrefetch {
+			return
+		}
+		rb.add(e)
+	}
+	readAll := func(name string, r io.Reader) {
+		if err := readLogEvents(r, add); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", name, err)
+			os.Exit(1)
+		}
+	}
+	if fs.NArg() == 0 {
+		readAll("stdin", os.Stdin)
+	}
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		readAll(path, f)
+		f.Close()
+	}
+
+	rep := rb.finish()
+
+	switch *format {
+	case "text":
+		rep.writeText(os.Stdout, *top)
+	case "csv":
+		if err := rep.writeCSV(os.Stdout, *table, *top); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format %q\n", *format)
+		os.Exit(2)
+	}
+}
+
+func parseReportTime(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// readLogEvents calls fn with each event in r, which may be gzipped as
+// rotated query logs are.
+func readLogEvents(r io.Reader, fn func(logEvent)) error {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		r = zr
+	} else {
+		r = br
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		// query logs on stderr may be interleaved with other log lines
+		if len(line) == 0 || line[0] != '{' {
+			continue
+		}
+		var e logEvent
+		if err := json.Unmarshal(line, &e); err != nil {
+			continue
+		}
+		fn(e)
+	}
+	return scanner.Err()
+}
+
+// reportRequestWindow is how long after its last event a request is
+// considered done. Until then its events are kept to be matched up.
+const reportRequestWindow = time.Minute
+
+// reportBuilder aggregates events into a report as they are read, so
+// only the requests still in progress are held in memory.
+type reportBuilder struct {
+	rep       *report
+	pending   map[string]*pendingRequest
+	lastSweep time.Time
+}
+
+// pendingRequest collects the events of one request.
+type pendingRequest struct {
+	last    time.Time
+	name    string
+	racers  []string
+	seen    map[string]bool // backends whose latency was recorded
+	winner  string
+	first   time.Duration
+	settled bool // a backend answered
+	// concurrent is set when a successful backend_result was logged,
+	// which only happens in Concurrent mode.
+	concurrent bool
+}
+
+func newReportBuilder() *reportBuilder {
+	return &reportBuilder{
+		rep: &report{
+			backends: make(map[string]*backendReport),
+			names:    make(map[string]*nameReport),
+		},
+		pending: make(map[string]*pendingRequest),
+	}
+}
+
+func (rb *reportBuilder) backend(name string) *backendReport {
+	b := rb.rep.backends[name]
+	if b == nil {
+		b = &backendReport{Backend: name, ErrorKind: make(map[string]int)}
+		rb.rep.backends[name] = b
+	}
+	return b
+}
+
+func (rb *reportBuilder) add(e logEvent) {
+	if e.TS.Sub(rb.lastSweep) > reportRequestWindow {
+		rb.sweep(e.TS.Add(-reportRequestWindow))
+		rb.lastSweep = e.TS
+	}
+
+	d := time.Duration(e.DurationUS) * time.Microsecond
+	switch e.Evt {
+	case "request":
+		req := rb.request(e)
+		rb.rep.requests++
+		if e.QName != "" {
+			req.name = strings.ToLower(e.QName)
+		} else if qs := parseReqQuestions(e.Req); len(qs) > 0 {
+			req.name = strings.ToLower(qs[0].name)
+		}
+	case "backend_result":
+		req := rb.request(e)
+		b := rb.backend(e.Backend)
+		req.racers = append(req.racers, e.Backend)
+		if errStr := logEventError(e.Error); errStr != "" {
+			b.Results++
+			b.Errors++
+			b.ErrorKind[errorKind(errStr)]++
+			return
+		}
+		req.concurrent = true
+		if !req.seen[e.Backend] {
+			req.seen[e.Backend] = true
+			b.Results++
+			b.Latencies = append(b.Latencies, d)
+		}
+	case "first_result":
+		// In serial modes the winning exchange is only logged as the
+		// first result.
+		req := rb.request(e)
+		if !req.seen[e.Backend] {
+			req.seen[e.Backend] = true
+			b := rb.backend(e.Backend)
+			b.Results++
+			b.Latencies = append(b.Latencies, d)
+		}
+		req.winner = e.Backend
+		req.first = d
+		req.settled = true
+	case "query_failure":
+		rb.rep.failures++
+	}
+}
+
+// request returns the pending request e belongs to.
+func (rb *reportBuilder) request(e logEvent) *pendingRequest {
+	req := rb.pending[e.ID]
+	if req == nil {
+		req = &pendingRequest{seen: make(map[string]bool)}
+		rb.pending[e.ID] = req
+	}
+	if e.TS.After(req.last) {
+		req.last = e.TS
+	}
+	return req
+}
+
+// sweep finishes the requests with no events since before.
+func (rb *reportBuilder) sweep(before time.Time) {
+	for id, req := range rb.pending {
+		if req.last.Before(before) {
+			rb.finishRequest(req)
+			delete(rb.pending, id)
+		}
+	}
+}
+
+func (rb *reportBuilder) finishRequest(req *pendingRequest) {
+	// Only backends queried at the same time race each other; in the
+	// serial modes the later backends never get a chance to win.
+	if req.concurrent && len(req.racers) >= 2 {
+		for _, name := range req.racers {
+			b := rb.backend(name)
+			b.Races++
+			if req.winner == name {
+				b.Wins++
+			}
+		}
+	}
+
+	if req.name != "" && req.settled {
+		n := rb.rep.names[req.name]
+		if n == nil {
+			n = &nameReport{Name: req.name}
+			rb.rep.names[req.name] = n
+		}
+		n.Latencies = append(n.Latencies, req.first)
+	}
+}
+
+// finish finishes every request still pending and returns the report.
+func (rb *reportBuilder) finish() *report {
+	for id, req := range rb.pending {
+		rb.finishRequest(req)
+		delete(rb.pending, id)
+	}
+
+	for _, b := range rb.rep.backends {
+		sortDurations(b.Latencies)
+	}
+	for _, n := range rb.rep.names {
+		sortDurations(n.Latencies)
+	}
+	return rb.rep
+}
+
+// logEventError returns the error recorded in an event. Older logs
+// encoded errors as an empty JSON object, which only tells us that the
+// exchange failed.
+func logEventError(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return "unknown error"
+}
+
+// errorKind strips the addresses and ports that make otherwise identical
+// network errors distinct, keeping only the final cause.
+func errorKind(err string) string {
+	if i := strings.LastIndex(err, ": "); i >= 0 {
+		return err[i+2:]
+	}
+	return err
+}
+
+func (rep *report) sortedBackends() []*backendReport {
+	list := make([]*backendReport, 0, len(rep.backends))
+	for _, b := range rep.backends {
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Backend < list[j].Backend })
+	return list
+}
+
+func (rep *report) slowestNames(top int) []*nameReport {
+	list := make([]*nameReport, 0, len(rep.names))
+	for _, n := range rep.names {
+		list = append(list, n)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return percentile(list[i].Latencies, 50) > percentile(list[j].Latencies, 50)
+	})
+	if len(list) > top {
+		list = list[:top]
+	}
+	return list
+}
+
+func (b *backendReport) winRate() string {
+	if b.Races == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", 100*float64(b.Wins)/float64(b.Races))
+}
+
+func (b *backendReport) errorRate() string {
+	if b.Results == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", 100*float64(b.Errors)/float64(b.Results))
+}
+
+func (rep *report) writeText(w io.Writer, top int) {
+	fmt.Fprintf(w, "requests: %d  failed: %d\n\n", rep.requests, rep.failures)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "BACKEND\tRESULTS\tERR%\tWIN%\tP50\tP90\tP99\t")
+	for _, b := range rep.sortedBackends() {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t\n", b.Backend, b.Results, b.errorRate(), b.winRate(),
+			percentile(b.Latencies, 50), percentile(b.Latencies, 90), percentile(b.Latencies, 99))
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "BACKEND\tERROR\tCOUNT\t")
+	for _, b := range rep.sortedBackends() {
+		for _, kind := range sortedKeys(b.ErrorKind) {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t\n", b.Backend, kind, b.ErrorKind[kind])
+		}
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tQUERIES\tP50\tMAX\t")
+	for _, n := range rep.slowestNames(top) {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t\n", n.Name, len(n.Latencies),
+			percentile(n.Latencies, 50), percentile(n.Latencies, 100))
+	}
+	tw.Flush()
+}
+
+func (rep *report) writeCSV(w io.Writer, table string, top int) error {
+	cw := csv.NewWriter(w)
+	us := func(d time.Duration) string {
+		return strconv.FormatInt(d.Microseconds(), 10)
+	}
+
+	switch table {
+	case "backends":
+		cw.Write([]string{"backend", "results", "errors", "races", "wins", "p50_us", "p90_us", "p99_us"})
+		for _, b := range rep.sortedBackends() {
+			cw.Write([]string{b.Backend, strconv.Itoa(b.Results), strconv.Itoa(b.Errors),
+				strconv.Itoa(b.Races), strconv.Itoa(b.Wins),
+				us(percentile(b.Latencies, 50)), us(percentile(b.Latencies, 90)), us(percentile(b.Latencies, 99))})
+		}
+	case "errors":
+		cw.Write([]string{"backend", "error", "count"})
+		for _, b := range rep.sortedBackends() {
+			for _, kind := range sortedKeys(b.ErrorKind) {
+				cw.Write([]string{b.Backend, kind, strconv.Itoa(b.ErrorKind[kind])})
+			}
+		}
+	case "names":
+		cw.Write([]string{"name", "queries", "p50_us", "max_us"})
+		for _, n := range rep.slowestNames(top) {
+			cw.Write([]string{n.Name, strconv.Itoa(len(n.Latencies)),
+				us(percentile(n.Latencies, 50)), us(percentile(n.Latencies, 100))})
+		}
+	default:
+		return errors.New("unknown table " + strconv.Quote(table))
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
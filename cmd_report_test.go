@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+	"time"
+)
+
+const reportLog = `2024/01/02 15:04:05.000000 running
+{"ts":"2024-01-02T15:04:05Z","evt":"request","id":"1-1","req":"dnsmsg{ q=example.com./IN/A}"}
+{"ts":"2024-01-02T15:04:05Z","evt":"first_result","id":"1-1","duration_us":1000,"backend":"fast","result":""}
+{"ts":"2024-01-02T15:04:05Z","evt":"backend_result","id":"1-1","duration_us":1000,"backend":"fast","req":""}
+{"ts":"2024-01-02T15:04:05Z","evt":"backend_result","id":"1-1","duration_us":9000,"backend":"slow","req":""}
+{"ts":"2024-01-02T15:04:06Z","evt":"request","id":"1-2","req":"dnsmsg{ q=slow.example./IN/AAAA}"}
+{"ts":"2024-01-02T15:04:06Z","evt":"backend_result","id":"1-2","duration_us":2000000,"backend":"fast","error":{},"req":""}
+{"ts":"2024-01-02T15:04:06Z","evt":"backend_result","id":"1-2","duration_us":30000,"backend":"slow","error":"read udp 10.0.0.1:5353->8.8.8.8:53: i/o timeout","req":""}
+{"ts":"2024-01-02T15:04:06Z","evt":"query_failure","id":"1-2","req":"","backend_count":2}
+{"ts":"2024-01-02T15:10:00Z","evt":"request","id":"1-3","qname":"serial.example.","req":""}
+{"ts":"2024-01-02T15:10:00Z","evt":"backend_result","id":"1-3","duration_us":1000,"backend":"fast","error":"dial tcp 10.0.0.2:53: connection refused","req":""}
+{"ts":"2024-01-02T15:10:00Z","evt":"backend_result","id":"1-3","duration_us":1000,"backend":"slow","error":"dial tcp 10.0.0.3:53: connection refused","req":""}
+{"ts":"2024-01-02T15:10:00Z","evt":"first_result","id":"1-3","duration_us":3000,"backend":"third","result":""}
+`
+
+func TestBuildReport(t *testing.T) {
+	rb := newReportBuilder()
+	if err := readLogEvents(strings.NewReader(reportLog), rb.add); err != nil {
+		t.Fatal(err)
+	}
+	// The first requests are done by the time of the last one.
+	if len(rb.pending) != 1 {
+		t.Errorf("expected 1 pending request, got %d", len(rb.pending))
+	}
+	rep := rb.finish()
+
+	if rep.requests != 3 || rep.failures != 1 {
+		t.Errorf("got %d requests %d failures", rep.requests, rep.failures)
+	}
+
+	fast := rep.backends["fast"]
+	// Only the first request was resolved concurrently, so it is the
+	// only race.
+	if fast.Results != 3 || fast.Errors != 2 || fast.Wins != 1 || fast.Races != 1 {
+		t.Errorf("unexpected fast backend stats: %+v", fast)
+	}
+	if len(fast.Latencies) != 1 || fast.Latencies[0] != time.Millisecond {
+		t.Errorf("unexpected fast latencies: %v", fast.Latencies)
+	}
+	if fast.ErrorKind["unknown error"] != 1 {
+		t.Errorf("unexpected fast errors: %v", fast.ErrorKind)
+	}
+
+	slow := rep.backends["slow"]
+	if slow.Wins != 0 || slow.Races != 1 || slow.ErrorKind["i/o timeout"] != 1 {
+		t.Errorf("unexpected slow backend stats: %+v", slow)
+	}
+	if third := rep.backends["third"]; third.Results != 1 || third.winRate() != "-" {
+		t.Errorf("unexpected third backend stats: %+v", third)
+	}
+
+	names := rep.slowestNames(10)
+	if len(names) != 2 || names[0].Name != "serial.example." || names[1].Name != "example.com." {
+		t.Errorf("unexpected names: %+v", names)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(reportLog))
+	zw.Close()
+	rb = newReportBuilder()
+	if err := readLogEvents(&buf, rb.add); err != nil {
+		t.Fatal(err)
+	}
+	if rep := rb.finish(); rep.requests != 3 || len(rep.backends) != 3 {
+		t.Errorf("unexpected report from gzipped log: %d requests, %d backends", rep.requests, len(rep.backends))
+	}
+}
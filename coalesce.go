@@ -0,0 +1,77 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// inflight deduplicates identical upstream queries that arrive while an
+// earlier one is still outstanding. The first caller (the leader) performs
+// the query; callers that arrive before it completes wait for and share
+// its result.
+type inflight struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+}
+
+type inflightCall struct {
+	done     chan struct{}
+	leaderID string
+	resp     *dns.Msg
+	err      error
+}
+
+// do runs fn for key unless an identical call is already in flight, in
+// which case it waits for that call instead. It returns the id of the
+// request that performed the query. The returned message may be shared
+// with other callers and must be copied before it is modified when
+// leaderID != id.
+func (g *inflight) do(key, id string, fn func() (*dns.Msg, error)) (resp *dns.Msg, leaderID string, err error) {
+	if key == "" {
+		resp, err = fn()
+		return resp, id, err
+	}
+
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*inflightCall)
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-c.done
+		return c.resp, c.leaderID, c.err
+	}
+	c := &inflightCall{
+		done:     make(chan struct{}),
+		leaderID: id,
+	}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.resp, c.err = fn()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(c.done)
+
+	return c.resp, c.leaderID, c.err
+}
+
+// coalesceKey returns a key that is identical for queries that would
+// produce the same upstream answer. It is the packed query with the
+// message id zeroed, so the question, header flags and EDNS options
+// all have to match, followed by the client subnet scope from ecsScope.
+// An empty key disables coalescing for the query.
+func coalesceKey(r *dns.Msg, scope string) string {
+	// Pack writes to the OPT record, so work on a copy to avoid racing
+	// with other readers of r.
+	m := r.Copy()
+	m.Id = 0
+	b, err := m.Pack()
+	if err != nil {
+		return ""
+	}
+	return string(b) + scope
+}
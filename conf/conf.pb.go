@@ -51,6 +51,59 @@ func (Config_ResolveMode) EnumDescriptor() ([]byte, []int) {
 	return fileDescriptor_0b6ecbfc68e85c65, []int{0, 0}
 }
 
+type Ecs_Mode int32
+
+const (
+	Ecs_STRIP Ecs_Mode = 0
+	Ecs_ADD   Ecs_Mode = 1
+	Ecs_PASS  Ecs_Mode = 2
+)
+
+var Ecs_Mode_name = map[int32]string{
+	0: "STRIP",
+	1: "ADD",
+	2: "PASS",
+}
+
+var Ecs_Mode_value = map[string]int32{
+	"STRIP": 0,
+	"ADD":   1,
+	"PASS":  2,
+}
+
+func (x Ecs_Mode) String() string {
+	return proto.EnumName(Ecs_Mode_name, int32(x))
+}
+
+func (Ecs_Mode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_0b6ecbfc68e85c65, []int{1, 0}
+}
+
+type RebindProtection_Action int32
+
+const (
+	RebindProtection_STRIP  RebindProtection_Action = 0
+	RebindProtection_REFUSE RebindProtection_Action = 1
+)
+
+var RebindProtection_Action_name = map[int32]string{
+	0: "STRIP",
+	1: "REFUSE",
+}
+
+var RebindProtection_Action_value = map[string]int32{
+	"STRIP":  0,
+	"REFUSE": 1,
+}
+
+func (x RebindProtection_Action) String() string {
+	return proto.EnumName(RebindProtection_Action_name, int32(x))
+}
+
+func (RebindProtection_Action) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_0b6ecbfc68e85c65, []int{2, 0}
+}
+
 type Server_Type int32
 
 const (
@@ -73,18 +126,55 @@ func (x Server_Type) String() string {
 }
 
 func (Server_Type) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_0b6ecbfc68e85c65, []int{1, 0}
+	return fileDescriptor_0b6ecbfc68e85c65, []int{9, 0}
+}
+
+type Server_Method int32
+
+const (
+	Server_POST Server_Method = 0
+	Server_GET  Server_Method = 1
+)
+
+var Server_Method_name = map[int32]string{
+	0: "POST",
+	1: "GET",
+}
+
+var Server_Method_value = map[string]int32{
+	"POST": 0,
+	"GET":  1,
+}
+
+func (x Server_Method) String() string {
+	return proto.EnumName(Server_Method_name, int32(x))
+}
+
+func (Server_Method) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_0b6ecbfc68e85c65, []int{9, 1}
 }
 
 type Config struct {
-	Servers              []Server           `protobuf:"bytes,1,rep,name=server,proto3" json:"server"`
-	ResolveMode          Config_ResolveMode `protobuf:"varint,2,opt,name=resolve_mode,json=resolveMode,proto3,enum=conf.Config_ResolveMode" json:"resolve_mode,omitempty"`
-	ListenAddr           string             `protobuf:"bytes,3,opt,name=listen_addr,json=listenAddr,proto3" json:"listen_addr,omitempty"`
-	LogQueries           bool               `protobuf:"varint,4,opt,name=log_queries,json=logQueries,proto3" json:"log_queries,omitempty"`
-	OverrideFile         string             `protobuf:"bytes,5,opt,name=override_file,json=overrideFile,proto3" json:"override_file,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
-	XXX_unrecognized     []byte             `json:"-"`
-	XXX_sizecache        int32              `json:"-"`
+	Servers               []Server           `protobuf:"bytes,1,rep,name=server,proto3" json:"server"`
+	ResolveMode           Config_ResolveMode `protobuf:"varint,2,opt,name=resolve_mode,json=resolveMode,proto3,enum=conf.Config_ResolveMode" json:"resolve_mode,omitempty"`
+	ListenAddr            string             `protobuf:"bytes,3,opt,name=listen_addr,json=listenAddr,proto3" json:"listen_addr,omitempty"`
+	LogQueries            bool               `protobuf:"varint,4,opt,name=log_queries,json=logQueries,proto3" json:"log_queries,omitempty"`
+	OverrideFile          string             `protobuf:"bytes,5,opt,name=override_file,json=overrideFile,proto3" json:"override_file,omitempty"`
+	ReloadIntervalSeconds uint32             `protobuf:"varint,6,opt,name=reload_interval_seconds,json=reloadIntervalSeconds,proto3" json:"reload_interval_seconds,omitempty"`
+	Cache                 *Cache             `protobuf:"bytes,7,opt,name=cache,proto3" json:"cache,omitempty"`
+	Admin                 *Admin             `protobuf:"bytes,8,opt,name=admin,proto3" json:"admin,omitempty"`
+	QueryLog              *QueryLog          `protobuf:"bytes,9,opt,name=query_log,json=queryLog,proto3" json:"query_log,omitempty"`
+	Dnstap                *Dnstap            `protobuf:"bytes,10,opt,name=dnstap,proto3" json:"dnstap,omitempty"`
+	Tracing               *Tracing           `protobuf:"bytes,11,opt,name=tracing,proto3" json:"tracing,omitempty"`
+	Dnssec                *Dnssec            `protobuf:"bytes,12,opt,name=dnssec,proto3" json:"dnssec,omitempty"`
+	RebindProtection      *RebindProtection  `protobuf:"bytes,13,opt,name=rebind_protection,json=rebindProtection,proto3" json:"rebind_protection,omitempty"`
+	Ecs                   *Ecs               `protobuf:"bytes,14,opt,name=ecs,proto3" json:"ecs,omitempty"`
+	// bootstrap_servers (ip:port) look up the doh_url host of DOH servers
+	// without a host_port using plain DNS.
+	BootstrapServers     []string `protobuf:"bytes,15,rep,name=bootstrap_servers,json=bootstrapServers,proto3" json:"bootstrap_servers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *Config) Reset()         { *m = Config{} }
@@ -155,28 +245,102 @@ func (m *Config) GetOverrideFile() string {
 	return ""
 }
 
-type Server struct {
-	Name                 string      `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Type                 Server_Type `protobuf:"varint,2,opt,name=type,proto3,enum=conf.Server_Type" json:"type,omitempty"`
-	HostPort             string      `protobuf:"bytes,3,opt,name=host_port,json=hostPort,proto3" json:"host_port,omitempty"`
-	DohUrl               string      `protobuf:"bytes,4,opt,name=doh_url,json=dohUrl,proto3" json:"doh_url,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
-	XXX_unrecognized     []byte      `json:"-"`
-	XXX_sizecache        int32       `json:"-"`
+func (m *Config) GetReloadIntervalSeconds() uint32 {
+	if m != nil {
+		return m.ReloadIntervalSeconds
+	}
+	return 0
 }
 
-func (m *Server) Reset()         { *m = Server{} }
-func (m *Server) String() string { return proto.CompactTextString(m) }
-func (*Server) ProtoMessage()    {}
-func (*Server) Descriptor() ([]byte, []int) {
+func (m *Config) GetCache() *Cache {
+	if m != nil {
+		return m.Cache
+	}
+	return nil
+}
+
+func (m *Config) GetAdmin() *Admin {
+	if m != nil {
+		return m.Admin
+	}
+	return nil
+}
+
+func (m *Config) GetQueryLog() *QueryLog {
+	if m != nil {
+		return m.QueryLog
+	}
+	return nil
+}
+
+func (m *Config) GetDnstap() *Dnstap {
+	if m != nil {
+		return m.Dnstap
+	}
+	return nil
+}
+
+func (m *Config) GetTracing() *Tracing {
+	if m != nil {
+		return m.Tracing
+	}
+	return nil
+}
+
+func (m *Config) GetDnssec() *Dnssec {
+	if m != nil {
+		return m.Dnssec
+	}
+	return nil
+}
+
+func (m *Config) GetRebindProtection() *RebindProtection {
+	if m != nil {
+		return m.RebindProtection
+	}
+	return nil
+}
+
+func (m *Config) GetEcs() *Ecs {
+	if m != nil {
+		return m.Ecs
+	}
+	return nil
+}
+
+func (m *Config) GetBootstrapServers() []string {
+	if m != nil {
+		return m.BootstrapServers
+	}
+	return nil
+}
+
+// Ecs controls the EDNS Client Subnet option (RFC 7871) sent upstream.
+type Ecs struct {
+	Mode       Ecs_Mode `protobuf:"varint,1,opt,name=mode,proto3,enum=conf.Ecs_Mode" json:"mode,omitempty"`
+	Ipv4Prefix uint32   `protobuf:"varint,2,opt,name=ipv4_prefix,json=ipv4Prefix,proto3" json:"ipv4_prefix,omitempty"`
+	Ipv6Prefix uint32   `protobuf:"varint,3,opt,name=ipv6_prefix,json=ipv6Prefix,proto3" json:"ipv6_prefix,omitempty"`
+	// address is sent in ADD mode instead of the client's address, for
+	// example this network's public ip. Without it, clients with private
+	// addresses get no client subnet.
+	Address              string   `protobuf:"bytes,4,opt,name=address,proto3" json:"address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Ecs) Reset()         { *m = Ecs{} }
+func (m *Ecs) String() string { return proto.CompactTextString(m) }
+func (*Ecs) ProtoMessage()    {}
+func (*Ecs) Descriptor() ([]byte, []int) {
 	return fileDescriptor_0b6ecbfc68e85c65, []int{1}
 }
-func (m *Server) XXX_Unmarshal(b []byte) error {
+func (m *Ecs) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
 }
-func (m *Server) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+func (m *Ecs) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 	if deterministic {
-		return xxx_messageInfo_Server.Marshal(b, m, deterministic)
+		return xxx_messageInfo_Ecs.Marshal(b, m, deterministic)
 	} else {
 		b = b[:cap(b)]
 		n, err := m.MarshalToSizedBuffer(b)
@@ -186,283 +350,3728 @@ func (m *Server) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 		return b[:n], nil
 	}
 }
-func (m *Server) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Server.Merge(m, src)
+func (m *Ecs) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Ecs.Merge(m, src)
 }
-func (m *Server) XXX_Size() int {
+func (m *Ecs) XXX_Size() int {
 	return m.Size()
 }
-func (m *Server) XXX_DiscardUnknown() {
-	xxx_messageInfo_Server.DiscardUnknown(m)
+func (m *Ecs) XXX_DiscardUnknown() {
+	xxx_messageInfo_Ecs.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_Server proto.InternalMessageInfo
+var xxx_messageInfo_Ecs proto.InternalMessageInfo
 
-func (m *Server) GetName() string {
+func (m *Ecs) GetMode() Ecs_Mode {
 	if m != nil {
-		return m.Name
+		return m.Mode
 	}
-	return ""
+	return Ecs_STRIP
 }
 
-func (m *Server) GetType() Server_Type {
+func (m *Ecs) GetIpv4Prefix() uint32 {
 	if m != nil {
-		return m.Type
+		return m.Ipv4Prefix
 	}
-	return Server_UDP
+	return 0
 }
 
-func (m *Server) GetHostPort() string {
+func (m *Ecs) GetIpv6Prefix() uint32 {
 	if m != nil {
-		return m.HostPort
+		return m.Ipv6Prefix
 	}
-	return ""
+	return 0
 }
 
-func (m *Server) GetDohUrl() string {
+func (m *Ecs) GetAddress() string {
 	if m != nil {
-		return m.DohUrl
+		return m.Address
 	}
 	return ""
 }
 
-func init() {
-	proto.RegisterEnum("conf.Config_ResolveMode", Config_ResolveMode_name, Config_ResolveMode_value)
-	proto.RegisterEnum("conf.Server_Type", Server_Type_name, Server_Type_value)
-	proto.RegisterType((*Config)(nil), "conf.Config")
-	proto.RegisterType((*Server)(nil), "conf.Server")
+type RebindProtection struct {
+	Action RebindProtection_Action `protobuf:"varint,1,opt,name=action,proto3,enum=conf.RebindProtection_Action" json:"action,omitempty"`
+	// blocked_cidrs are the address ranges public names may not resolve
+	// to. Defaults to the private, loopback, link-local, CGNAT and
+	// unspecified ranges for IPv4 and IPv6.
+	BlockedCidrs []string `protobuf:"bytes,2,rep,name=blocked_cidrs,json=blockedCidrs,proto3" json:"blocked_cidrs,omitempty"`
+	// allowed_domains may resolve to blocked addresses, along with every
+	// name below them. Names with an override entry are always allowed.
+	AllowedDomains       []string `protobuf:"bytes,3,rep,name=allowed_domains,json=allowedDomains,proto3" json:"allowed_domains,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func init() { proto.RegisterFile("conf.proto", fileDescriptor_0b6ecbfc68e85c65) }
-
-var fileDescriptor_0b6ecbfc68e85c65 = []byte{
-	// 377 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x91, 0xc1, 0x6e, 0xd3, 0x40,
-	0x10, 0x86, 0xb3, 0x89, 0x71, 0x9a, 0x71, 0x28, 0x66, 0x85, 0xc4, 0x0a, 0xa4, 0xc4, 0x32, 0x42,
-	0xf2, 0xc9, 0x48, 0x45, 0x70, 0xe1, 0x44, 0x5b, 0x21, 0x38, 0xa0, 0x96, 0x85, 0x9e, 0xad, 0xd0,
-	0x9d, 0x38, 0x96, 0x9c, 0x1d, 0x33, 0x76, 0x22, 0xf5, 0x39, 0x38, 0xf2, 0x42, 0x3d, 0xf2, 0x04,
-	0x15, 0xf2, 0x93, 0x20, 0xef, 0xb6, 0x6a, 0x6f, 0xff, 0x7c, 0xf3, 0xef, 0xaf, 0xfd, 0x77, 0x01,
-	0x2e, 0xc9, 0xae, 0xf3, 0x86, 0xa9, 0x23, 0x19, 0x0c, 0xfa, 0xc5, 0xb3, 0x92, 0x4a, 0x72, 0xe0,
-	0xcd, 0xa0, 0xfc, 0x2e, 0xfd, 0x33, 0x86, 0xf0, 0x84, 0xec, 0xba, 0x2a, 0xe5, 0x3b, 0x08, 0x5b,
-	0xe4, 0x3d, 0xb2, 0x12, 0xc9, 0x24, 0x8b, 0x8e, 0xe6, 0xb9, 0xcb, 0xf8, 0xee, 0xd8, 0xf1, 0x93,
-	0xeb, 0x9b, 0xe5, 0xa8, 0xbf, 0x59, 0x4e, 0xfd, 0xdc, 0xea, 0x5b, 0xb3, 0xfc, 0x00, 0x73, 0xc6,
-	0x96, 0xea, 0x3d, 0x16, 0x5b, 0x32, 0xa8, 0xc6, 0x89, 0xc8, 0x0e, 0x8f, 0x94, 0x3f, 0xec, 0xa3,
-	0x73, 0xed, 0x0d, 0x5f, 0xc9, 0xa0, 0x8e, 0xf8, 0x7e, 0x90, 0x4b, 0x88, 0xea, 0xaa, 0xed, 0xd0,
-	0x16, 0x2b, 0x63, 0x58, 0x4d, 0x12, 0x91, 0xcd, 0x34, 0x78, 0xf4, 0xd1, 0x18, 0x76, 0x06, 0x2a,
-	0x8b, 0x5f, 0x3b, 0xe4, 0x0a, 0x5b, 0x15, 0x24, 0x22, 0x3b, 0xd0, 0x50, 0x53, 0xf9, 0xcd, 0x13,
-	0xf9, 0x0a, 0x1e, 0xd3, 0x1e, 0x99, 0x2b, 0x83, 0xc5, 0xba, 0xaa, 0x51, 0x3d, 0x72, 0x19, 0xf3,
-	0x3b, 0xf8, 0xa9, 0xaa, 0x31, 0x7d, 0x0f, 0xd1, 0x83, 0x2b, 0x48, 0x80, 0x50, 0xaf, 0xac, 0xa1,
-	0x6d, 0x3c, 0x92, 0x11, 0x4c, 0xbf, 0xd8, 0x33, 0x36, 0xc8, 0xb1, 0x90, 0x87, 0x00, 0x27, 0x64,
-	0x2f, 0x77, 0xcc, 0x68, 0xbb, 0x78, 0x9c, 0xfe, 0x16, 0x10, 0xfa, 0xbe, 0x52, 0x42, 0x60, 0x57,
-	0x5b, 0x54, 0xc2, 0xc5, 0x3b, 0x2d, 0x5f, 0x43, 0xd0, 0x5d, 0x35, 0x77, 0x95, 0x9f, 0x3e, 0x7c,
-	0xaf, 0xfc, 0xc7, 0x55, 0x83, 0xda, 0xad, 0xe5, 0x4b, 0x98, 0x6d, 0xa8, 0xed, 0x8a, 0x86, 0xb8,
-	0xbb, 0xad, 0x78, 0x30, 0x80, 0x73, 0xe2, 0x4e, 0x3e, 0x87, 0xa9, 0xa1, 0x4d, 0xb1, 0xe3, 0xda,
-	0x95, 0x9b, 0xe9, 0xd0, 0xd0, 0xe6, 0x82, 0xeb, 0x54, 0x41, 0x30, 0x64, 0xc8, 0x29, 0x4c, 0x2e,
-	0x4e, 0xcf, 0xe3, 0xd1, 0x20, 0x4e, 0xcf, 0x3e, 0xc7, 0xe2, 0x78, 0x7e, 0xdd, 0x2f, 0xc4, 0xdf,
-	0x7e, 0x21, 0xfe, 0xf5, 0x0b, 0xf1, 0x33, 0x74, 0x1f, 0xf9, 0xf6, 0x7f, 0x00, 0x00, 0x00, 0xff,
-	0xff, 0x18, 0x0e, 0x6e, 0xf0, 0xf2, 0x01, 0x00, 0x00,
+func (m *RebindProtection) Reset()         { *m = RebindProtection{} }
+func (m *RebindProtection) String() string { return proto.CompactTextString(m) }
+func (*RebindProtection) ProtoMessage()    {}
+func (*RebindProtection) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b6ecbfc68e85c65, []int{2}
 }
-
-func (m *Config) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+func (m *RebindProtection) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *RebindProtection) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_RebindProtection.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	return dAtA[:n], nil
+}
+func (m *RebindProtection) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RebindProtection.Merge(m, src)
+}
+func (m *RebindProtection) XXX_Size() int {
+	return m.Size()
+}
+func (m *RebindProtection) XXX_DiscardUnknown() {
+	xxx_messageInfo_RebindProtection.DiscardUnknown(m)
 }
 
-func (m *Config) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+var xxx_messageInfo_RebindProtection proto.InternalMessageInfo
+
+func (m *RebindProtection) GetAction() RebindProtection_Action {
+	if m != nil {
+		return m.Action
+	}
+	return RebindProtection_STRIP
 }
 
-func (m *Config) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+func (m *RebindProtection) GetBlockedCidrs() []string {
+	if m != nil {
+		return m.BlockedCidrs
 	}
-	if len(m.OverrideFile) > 0 {
-		i -= len(m.OverrideFile)
-		copy(dAtA[i:], m.OverrideFile)
-		i = encodeVarintConf(dAtA, i, uint64(len(m.OverrideFile)))
-		i--
-		dAtA[i] = 0x2a
+	return nil
+}
+
+func (m *RebindProtection) GetAllowedDomains() []string {
+	if m != nil {
+		return m.AllowedDomains
 	}
-	if m.LogQueries {
-		i--
-		if m.LogQueries {
-			dAtA[i] = 1
-		} else {
-			dAtA[i] = 0
+	return nil
+}
+
+type Dnssec struct {
+	// trust_anchors are DS records in presentation format, for example
+	// ". IN DS 20326 8 2 E06D...". Defaults to the root zone KSKs.
+	TrustAnchors         []string `protobuf:"bytes,1,rep,name=trust_anchors,json=trustAnchors,proto3" json:"trust_anchors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Dnssec) Reset()         { *m = Dnssec{} }
+func (m *Dnssec) String() string { return proto.CompactTextString(m) }
+func (*Dnssec) ProtoMessage()    {}
+func (*Dnssec) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b6ecbfc68e85c65, []int{3}
+}
+func (m *Dnssec) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Dnssec) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Dnssec.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
 		}
-		i--
-		dAtA[i] = 0x20
-	}
-	if len(m.ListenAddr) > 0 {
-		i -= len(m.ListenAddr)
-		copy(dAtA[i:], m.ListenAddr)
-		i = encodeVarintConf(dAtA, i, uint64(len(m.ListenAddr)))
-		i--
-		dAtA[i] = 0x1a
+		return b[:n], nil
 	}
-	if m.ResolveMode != 0 {
-		i = encodeVarintConf(dAtA, i, uint64(m.ResolveMode))
-		i--
-		dAtA[i] = 0x10
+}
+func (m *Dnssec) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Dnssec.Merge(m, src)
+}
+func (m *Dnssec) XXX_Size() int {
+	return m.Size()
+}
+func (m *Dnssec) XXX_DiscardUnknown() {
+	xxx_messageInfo_Dnssec.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Dnssec proto.InternalMessageInfo
+
+func (m *Dnssec) GetTrustAnchors() []string {
+	if m != nil {
+		return m.TrustAnchors
 	}
-	if len(m.Servers) > 0 {
-		for iNdEx := len(m.Servers) - 1; iNdEx >= 0; iNdEx-- {
-			{
-				size, err := m.Servers[iNdEx].MarshalToSizedBuffer(dAtA[:i])
-				if err != nil {
-					return 0, err
-				}
-				i -= size
-				i = encodeVarintConf(dAtA, i, uint64(size))
-			}
-			i--
-			dAtA[i] = 0xa
+	return nil
+}
+
+type Tracing struct {
+	// endpoint is the host:port of an OTLP/HTTP collector; defaults to
+	// localhost:4318.
+	Endpoint    string `protobuf:"bytes,1,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	Insecure    bool   `protobuf:"varint,2,opt,name=insecure,proto3" json:"insecure,omitempty"`
+	ServiceName string `protobuf:"bytes,3,opt,name=service_name,json=serviceName,proto3" json:"service_name,omitempty"`
+	// sample_percent is the percentage of queries traced; defaults to 100.
+	SamplePercent        uint32   `protobuf:"varint,4,opt,name=sample_percent,json=samplePercent,proto3" json:"sample_percent,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Tracing) Reset()         { *m = Tracing{} }
+func (m *Tracing) String() string { return proto.CompactTextString(m) }
+func (*Tracing) ProtoMessage()    {}
+func (*Tracing) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b6ecbfc68e85c65, []int{4}
+}
+func (m *Tracing) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Tracing) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Tracing.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
 		}
+		return b[:n], nil
 	}
-	return len(dAtA) - i, nil
+}
+func (m *Tracing) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Tracing.Merge(m, src)
+}
+func (m *Tracing) XXX_Size() int {
+	return m.Size()
+}
+func (m *Tracing) XXX_DiscardUnknown() {
+	xxx_messageInfo_Tracing.DiscardUnknown(m)
 }
 
-func (m *Server) Marshal() (dAtA []byte, err error) {
-	size := m.Size()
-	dAtA = make([]byte, size)
-	n, err := m.MarshalToSizedBuffer(dAtA[:size])
-	if err != nil {
-		return nil, err
+var xxx_messageInfo_Tracing proto.InternalMessageInfo
+
+func (m *Tracing) GetEndpoint() string {
+	if m != nil {
+		return m.Endpoint
 	}
-	return dAtA[:n], nil
+	return ""
 }
 
-func (m *Server) MarshalTo(dAtA []byte) (int, error) {
-	size := m.Size()
-	return m.MarshalToSizedBuffer(dAtA[:size])
+func (m *Tracing) GetInsecure() bool {
+	if m != nil {
+		return m.Insecure
+	}
+	return false
 }
 
-func (m *Server) MarshalToSizedBuffer(dAtA []byte) (int, error) {
-	i := len(dAtA)
-	_ = i
-	var l int
-	_ = l
-	if m.XXX_unrecognized != nil {
-		i -= len(m.XXX_unrecognized)
-		copy(dAtA[i:], m.XXX_unrecognized)
+func (m *Tracing) GetServiceName() string {
+	if m != nil {
+		return m.ServiceName
 	}
-	if len(m.DohUrl) > 0 {
-		i -= len(m.DohUrl)
-		copy(dAtA[i:], m.DohUrl)
-		i = encodeVarintConf(dAtA, i, uint64(len(m.DohUrl)))
-		i--
-		dAtA[i] = 0x22
+	return ""
+}
+
+func (m *Tracing) GetSamplePercent() uint32 {
+	if m != nil {
+		return m.SamplePercent
 	}
-	if len(m.HostPort) > 0 {
-		i -= len(m.HostPort)
-		copy(dAtA[i:], m.HostPort)
-		i = encodeVarintConf(dAtA, i, uint64(len(m.HostPort)))
-		i--
-		dAtA[i] = 0x1a
+	return 0
+}
+
+type Dnstap struct {
+	// socket_path is the unix socket of a dnstap collector. The connection
+	// is retried in the background if the collector is unavailable.
+	SocketPath string `protobuf:"bytes,1,opt,name=socket_path,json=socketPath,proto3" json:"socket_path,omitempty"`
+	// file_path writes dnstap frames to this file instead of a socket. Each
+	// start appends a new frame stream to the file.
+	FilePath             string   `protobuf:"bytes,2,opt,name=file_path,json=filePath,proto3" json:"file_path,omitempty"`
+	Identity             string   `protobuf:"bytes,3,opt,name=identity,proto3" json:"identity,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Dnstap) Reset()         { *m = Dnstap{} }
+func (m *Dnstap) String() string { return proto.CompactTextString(m) }
+func (*Dnstap) ProtoMessage()    {}
+func (*Dnstap) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b6ecbfc68e85c65, []int{5}
+}
+func (m *Dnstap) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Dnstap) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Dnstap.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	if m.Type != 0 {
-		i = encodeVarintConf(dAtA, i, uint64(m.Type))
-		i--
-		dAtA[i] = 0x10
+}
+func (m *Dnstap) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Dnstap.Merge(m, src)
+}
+func (m *Dnstap) XXX_Size() int {
+	return m.Size()
+}
+func (m *Dnstap) XXX_DiscardUnknown() {
+	xxx_messageInfo_Dnstap.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Dnstap proto.InternalMessageInfo
+
+func (m *Dnstap) GetSocketPath() string {
+	if m != nil {
+		return m.SocketPath
 	}
-	if len(m.Name) > 0 {
-		i -= len(m.Name)
-		copy(dAtA[i:], m.Name)
-		i = encodeVarintConf(dAtA, i, uint64(len(m.Name)))
-		i--
-		dAtA[i] = 0xa
+	return ""
+}
+
+func (m *Dnstap) GetFilePath() string {
+	if m != nil {
+		return m.FilePath
 	}
-	return len(dAtA) - i, nil
+	return ""
 }
 
-func encodeVarintConf(dAtA []byte, offset int, v uint64) int {
-	offset -= sovConf(v)
-	base := offset
-	for v >= 1<<7 {
-		dAtA[offset] = uint8(v&0x7f | 0x80)
-		v >>= 7
-		offset++
+func (m *Dnstap) GetIdentity() string {
+	if m != nil {
+		return m.Identity
 	}
-	dAtA[offset] = uint8(v)
-	return base
+	return ""
 }
-func (m *Config) Size() (n int) {
-	if m == nil {
-		return 0
+
+type QueryLog struct {
+	Path        string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	MaxSizeMb   uint32 `protobuf:"varint,2,opt,name=max_size_mb,json=maxSizeMb,proto3" json:"max_size_mb,omitempty"`
+	MaxAgeHours uint32 `protobuf:"varint,3,opt,name=max_age_hours,json=maxAgeHours,proto3" json:"max_age_hours,omitempty"`
+	MaxBackups  uint32 `protobuf:"varint,4,opt,name=max_backups,json=maxBackups,proto3" json:"max_backups,omitempty"`
+	Compress    bool   `protobuf:"varint,5,opt,name=compress,proto3" json:"compress,omitempty"`
+	// buffer_entries is how many events may be queued for the background
+	// writer before new events are dropped; defaults to 4096.
+	BufferEntries uint32 `protobuf:"varint,6,opt,name=buffer_entries,json=bufferEntries,proto3" json:"buffer_entries,omitempty"`
+	// fields limits events to these json fields, for example "qname",
+	// "qtype", "rcode" and "client_addr". ts, evt and id are always
+	// written. Defaults to every field.
+	Fields               []string `protobuf:"bytes,7,rep,name=fields,proto3" json:"fields,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *QueryLog) Reset()         { *m = QueryLog{} }
+func (m *QueryLog) String() string { return proto.CompactTextString(m) }
+func (*QueryLog) ProtoMessage()    {}
+func (*QueryLog) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b6ecbfc68e85c65, []int{6}
+}
+func (m *QueryLog) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *QueryLog) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_QueryLog.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	var l int
-	_ = l
-	if len(m.Servers) > 0 {
-		for _, e := range m.Servers {
-			l = e.Size()
-			n += 1 + l + sovConf(uint64(l))
+}
+func (m *QueryLog) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_QueryLog.Merge(m, src)
+}
+func (m *QueryLog) XXX_Size() int {
+	return m.Size()
+}
+func (m *QueryLog) XXX_DiscardUnknown() {
+	xxx_messageInfo_QueryLog.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_QueryLog proto.InternalMessageInfo
+
+func (m *QueryLog) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+func (m *QueryLog) GetMaxSizeMb() uint32 {
+	if m != nil {
+		return m.MaxSizeMb
+	}
+	return 0
+}
+
+func (m *QueryLog) GetMaxAgeHours() uint32 {
+	if m != nil {
+		return m.MaxAgeHours
+	}
+	return 0
+}
+
+func (m *QueryLog) GetMaxBackups() uint32 {
+	if m != nil {
+		return m.MaxBackups
+	}
+	return 0
+}
+
+func (m *QueryLog) GetCompress() bool {
+	if m != nil {
+		return m.Compress
+	}
+	return false
+}
+
+func (m *QueryLog) GetBufferEntries() uint32 {
+	if m != nil {
+		return m.BufferEntries
+	}
+	return 0
+}
+
+func (m *QueryLog) GetFields() []string {
+	if m != nil {
+		return m.Fields
+	}
+	return nil
+}
+
+type Cache struct {
+	MaxEntries uint32 `protobuf:"varint,1,opt,name=max_entries,json=maxEntries,proto3" json:"max_entries,omitempty"`
+	// serve_stale answers from expired entries when all backends fail or
+	// time out (RFC 8767).
+	ServeStale      bool   `protobuf:"varint,2,opt,name=serve_stale,json=serveStale,proto3" json:"serve_stale,omitempty"`
+	StaleTtlSeconds uint32 `protobuf:"varint,3,opt,name=stale_ttl_seconds,json=staleTtlSeconds,proto3" json:"stale_ttl_seconds,omitempty"`
+	MaxStaleSeconds uint32 `protobuf:"varint,4,opt,name=max_stale_seconds,json=maxStaleSeconds,proto3" json:"max_stale_seconds,omitempty"`
+	ClientTimeoutMs uint32 `protobuf:"varint,5,opt,name=client_timeout_ms,json=clientTimeoutMs,proto3" json:"client_timeout_ms,omitempty"`
+	// prefetch_min_hits enables refreshing entries in the background before
+	// they expire once they have been hit this many times.
+	PrefetchMinHits          uint32 `protobuf:"varint,6,opt,name=prefetch_min_hits,json=prefetchMinHits,proto3" json:"prefetch_min_hits,omitempty"`
+	PrefetchThresholdPercent uint32 `protobuf:"varint,7,opt,name=prefetch_threshold_percent,json=prefetchThresholdPercent,proto3" json:"prefetch_threshold_percent,omitempty"`
+	// persist_file is where the cache is saved on shutdown and every
+	// persist_interval_seconds (defaults to 300). It is loaded on startup.
+	PersistFile            string   `protobuf:"bytes,8,opt,name=persist_file,json=persistFile,proto3" json:"persist_file,omitempty"`
+	PersistIntervalSeconds uint32   `protobuf:"varint,9,opt,name=persist_interval_seconds,json=persistIntervalSeconds,proto3" json:"persist_interval_seconds,omitempty"`
+	XXX_NoUnkeyedLiteral   struct{} `json:"-"`
+	XXX_unrecognized       []byte   `json:"-"`
+	XXX_sizecache          int32    `json:"-"`
+}
+
+func (m *Cache) Reset()         { *m = Cache{} }
+func (m *Cache) String() string { return proto.CompactTextString(m) }
+func (*Cache) ProtoMessage()    {}
+func (*Cache) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b6ecbfc68e85c65, []int{7}
+}
+func (m *Cache) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Cache) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Cache.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
 		}
+		return b[:n], nil
 	}
-	if m.ResolveMode != 0 {
-		n += 1 + sovConf(uint64(m.ResolveMode))
+}
+func (m *Cache) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Cache.Merge(m, src)
+}
+func (m *Cache) XXX_Size() int {
+	return m.Size()
+}
+func (m *Cache) XXX_DiscardUnknown() {
+	xxx_messageInfo_Cache.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Cache proto.InternalMessageInfo
+
+func (m *Cache) GetMaxEntries() uint32 {
+	if m != nil {
+		return m.MaxEntries
 	}
-	l = len(m.ListenAddr)
-	if l > 0 {
-		n += 1 + l + sovConf(uint64(l))
+	return 0
+}
+
+func (m *Cache) GetServeStale() bool {
+	if m != nil {
+		return m.ServeStale
 	}
-	if m.LogQueries {
-		n += 2
+	return false
+}
+
+func (m *Cache) GetStaleTtlSeconds() uint32 {
+	if m != nil {
+		return m.StaleTtlSeconds
 	}
-	l = len(m.OverrideFile)
-	if l > 0 {
-		n += 1 + l + sovConf(uint64(l))
+	return 0
+}
+
+func (m *Cache) GetMaxStaleSeconds() uint32 {
+	if m != nil {
+		return m.MaxStaleSeconds
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+	return 0
+}
+
+func (m *Cache) GetClientTimeoutMs() uint32 {
+	if m != nil {
+		return m.ClientTimeoutMs
 	}
-	return n
+	return 0
 }
 
-func (m *Server) Size() (n int) {
-	if m == nil {
-		return 0
+func (m *Cache) GetPrefetchMinHits() uint32 {
+	if m != nil {
+		return m.PrefetchMinHits
 	}
-	var l int
-	_ = l
-	l = len(m.Name)
-	if l > 0 {
-		n += 1 + l + sovConf(uint64(l))
+	return 0
+}
+
+func (m *Cache) GetPrefetchThresholdPercent() uint32 {
+	if m != nil {
+		return m.PrefetchThresholdPercent
 	}
-	if m.Type != 0 {
-		n += 1 + sovConf(uint64(m.Type))
+	return 0
+}
+
+func (m *Cache) GetPersistFile() string {
+	if m != nil {
+		return m.PersistFile
 	}
-	l = len(m.HostPort)
-	if l > 0 {
-		n += 1 + l + sovConf(uint64(l))
+	return ""
+}
+
+func (m *Cache) GetPersistIntervalSeconds() uint32 {
+	if m != nil {
+		return m.PersistIntervalSeconds
 	}
-	l = len(m.DohUrl)
-	if l > 0 {
-		n += 1 + l + sovConf(uint64(l))
+	return 0
+}
+
+type Admin struct {
+	// listen_addr is a host:port to listen on with tcp, or an absolute path
+	// for a unix socket.
+	ListenAddr string `protobuf:"bytes,1,opt,name=listen_addr,json=listenAddr,proto3" json:"listen_addr,omitempty"`
+	// bearer_token must be sent as "Authorization: Bearer <token>" on every
+	// request. It is required for tcp listeners and optional for unix sockets.
+	BearerToken          string   `protobuf:"bytes,2,opt,name=bearer_token,json=bearerToken,proto3" json:"bearer_token,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Admin) Reset()         { *m = Admin{} }
+func (m *Admin) String() string { return proto.CompactTextString(m) }
+func (*Admin) ProtoMessage()    {}
+func (*Admin) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b6ecbfc68e85c65, []int{8}
+}
+func (m *Admin) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Admin) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Admin.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
 	}
-	if m.XXX_unrecognized != nil {
-		n += len(m.XXX_unrecognized)
+}
+func (m *Admin) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Admin.Merge(m, src)
+}
+func (m *Admin) XXX_Size() int {
+	return m.Size()
+}
+func (m *Admin) XXX_DiscardUnknown() {
+	xxx_messageInfo_Admin.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Admin proto.InternalMessageInfo
+
+func (m *Admin) GetListenAddr() string {
+	if m != nil {
+		return m.ListenAddr
+	}
+	return ""
+}
+
+func (m *Admin) GetBearerToken() string {
+	if m != nil {
+		return m.BearerToken
+	}
+	return ""
+}
+
+type Server struct {
+	Name string      `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type Server_Type `protobuf:"varint,2,opt,name=type,proto3,enum=conf.Server_Type" json:"type,omitempty"`
+	// host_port is the server's ip:port. DOH servers may list several,
+	// which are tried happy eyeballs style, or none when bootstrap_servers
+	// is set.
+	HostPort []string `protobuf:"bytes,3,rep,name=host_port,json=hostPort,proto3" json:"host_port,omitempty"`
+	DohUrl   string   `protobuf:"bytes,4,opt,name=doh_url,json=dohUrl,proto3" json:"doh_url,omitempty"`
+	Ecs      *Ecs     `protobuf:"bytes,5,opt,name=ecs,proto3" json:"ecs,omitempty"`
+	// padding_block_size pads DoH queries to a multiple of this many bytes
+	// (RFC 8467) so their size doesn't reveal the name; defaults to 128.
+	PaddingBlockSize uint32        `protobuf:"varint,6,opt,name=padding_block_size,json=paddingBlockSize,proto3" json:"padding_block_size,omitempty"`
+	DisablePadding   bool          `protobuf:"varint,7,opt,name=disable_padding,json=disablePadding,proto3" json:"disable_padding,omitempty"`
+	DohMethod        Server_Method `protobuf:"varint,8,opt,name=doh_method,json=dohMethod,proto3,enum=conf.Server_Method" json:"doh_method,omitempty"`
+	// http3 switches DoH queries to HTTP/3 once the server advertises it
+	// with Alt-Svc, falling back to HTTP/2 if UDP is blocked.
+	Http3 bool `protobuf:"varint,9,opt,name=http3,proto3" json:"http3,omitempty"`
+	// spki_pins only accepts DoH servers whose certificate chain contains
+	// one of these keys: base64 SHA-256 hashes of a SubjectPublicKeyInfo.
+	SpkiPins []string `protobuf:"bytes,10,rep,name=spki_pins,json=spkiPins,proto3" json:"spki_pins,omitempty"`
+	// ca_file is a PEM bundle used instead of the system roots to verify
+	// the DoH server's certificate.
+	CaFile string `protobuf:"bytes,11,opt,name=ca_file,json=caFile,proto3" json:"ca_file,omitempty"`
+	// client_cert_file and client_key_file are a PEM certificate and key
+	// presented to DoH servers that require mutual TLS.
+	ClientCertFile string `protobuf:"bytes,12,opt,name=client_cert_file,json=clientCertFile,proto3" json:"client_cert_file,omitempty"`
+	ClientKeyFile  string `protobuf:"bytes,13,opt,name=client_key_file,json=clientKeyFile,proto3" json:"client_key_file,omitempty"`
+	// proxy sends queries through a socks5://, socks5h:// or http://
+	// (CONNECT) proxy. Only socks5h and http proxies resolve host names;
+	// socks5 needs host_port. UDP servers are queried over TCP. Without credentials in the url, a
+	// SOCKS5 proxy gets the server name as its username, which Tor uses to
+	// isolate each server's streams.
+	Proxy                string   `protobuf:"bytes,14,opt,name=proxy,proto3" json:"proxy,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Server) Reset()         { *m = Server{} }
+func (m *Server) String() string { return proto.CompactTextString(m) }
+func (*Server) ProtoMessage()    {}
+func (*Server) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0b6ecbfc68e85c65, []int{9}
+}
+func (m *Server) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Server) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Server.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Server) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Server.Merge(m, src)
+}
+func (m *Server) XXX_Size() int {
+	return m.Size()
+}
+func (m *Server) XXX_DiscardUnknown() {
+	xxx_messageInfo_Server.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Server proto.InternalMessageInfo
+
+func (m *Server) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Server) GetType() Server_Type {
+	if m != nil {
+		return m.Type
+	}
+	return Server_UDP
+}
+
+func (m *Server) GetHostPort() []string {
+	if m != nil {
+		return m.HostPort
+	}
+	return nil
+}
+
+func (m *Server) GetDohUrl() string {
+	if m != nil {
+		return m.DohUrl
+	}
+	return ""
+}
+
+func (m *Server) GetEcs() *Ecs {
+	if m != nil {
+		return m.Ecs
+	}
+	return nil
+}
+
+func (m *Server) GetPaddingBlockSize() uint32 {
+	if m != nil {
+		return m.PaddingBlockSize
+	}
+	return 0
+}
+
+func (m *Server) GetDisablePadding() bool {
+	if m != nil {
+		return m.DisablePadding
+	}
+	return false
+}
+
+func (m *Server) GetDohMethod() Server_Method {
+	if m != nil {
+		return m.DohMethod
+	}
+	return Server_POST
+}
+
+func (m *Server) GetHttp3() bool {
+	if m != nil {
+		return m.Http3
+	}
+	return false
+}
+
+func (m *Server) GetSpkiPins() []string {
+	if m != nil {
+		return m.SpkiPins
+	}
+	return nil
+}
+
+func (m *Server) GetCaFile() string {
+	if m != nil {
+		return m.CaFile
+	}
+	return ""
+}
+
+func (m *Server) GetClientCertFile() string {
+	if m != nil {
+		return m.ClientCertFile
+	}
+	return ""
+}
+
+func (m *Server) GetClientKeyFile() string {
+	if m != nil {
+		return m.ClientKeyFile
+	}
+	return ""
+}
+
+func (m *Server) GetProxy() string {
+	if m != nil {
+		return m.Proxy
+	}
+	return ""
+}
+
+func init() {
+	proto.RegisterEnum("conf.Config_ResolveMode", Config_ResolveMode_name, Config_ResolveMode_value)
+	proto.RegisterEnum("conf.Ecs_Mode", Ecs_Mode_name, Ecs_Mode_value)
+	proto.RegisterEnum("conf.RebindProtection_Action", RebindProtection_Action_name, RebindProtection_Action_value)
+	proto.RegisterEnum("conf.Server_Type", Server_Type_name, Server_Type_value)
+	proto.RegisterEnum("conf.Server_Method", Server_Method_name, Server_Method_value)
+	proto.RegisterType((*Config)(nil), "conf.Config")
+	proto.RegisterType((*Ecs)(nil), "conf.Ecs")
+	proto.RegisterType((*RebindProtection)(nil), "conf.RebindProtection")
+	proto.RegisterType((*Dnssec)(nil), "conf.Dnssec")
+	proto.RegisterType((*Tracing)(nil), "conf.Tracing")
+	proto.RegisterType((*Dnstap)(nil), "conf.Dnstap")
+	proto.RegisterType((*QueryLog)(nil), "conf.QueryLog")
+	proto.RegisterType((*Cache)(nil), "conf.Cache")
+	proto.RegisterType((*Admin)(nil), "conf.Admin")
+	proto.RegisterType((*Server)(nil), "conf.Server")
+}
+
+func init() { proto.RegisterFile("conf.proto", fileDescriptor_0b6ecbfc68e85c65) }
+
+var fileDescriptor_0b6ecbfc68e85c65 = []byte{
+	// 1381 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x56, 0xdb, 0x6e, 0x1b, 0xb7,
+	0x16, 0xcd, 0x58, 0xf7, 0xad, 0x8b, 0x65, 0x9e, 0x9c, 0x64, 0x90, 0xe0, 0xd8, 0xca, 0x9c, 0x9c,
+	0x13, 0x21, 0x69, 0x5d, 0xc0, 0x69, 0x8d, 0x02, 0xed, 0x8b, 0x6f, 0x69, 0x82, 0xd4, 0x8d, 0x4a,
+	0x29, 0xcf, 0xc4, 0x68, 0x86, 0xd6, 0x10, 0x9e, 0x21, 0x27, 0x24, 0xe5, 0x5a, 0xf9, 0x85, 0xf6,
+	0xb1, 0x7f, 0xd1, 0x1f, 0xe8, 0x27, 0x04, 0x7d, 0xea, 0x17, 0x04, 0x85, 0xbf, 0xa4, 0xe0, 0x65,
+	0x14, 0xd7, 0xc9, 0x93, 0x87, 0x6b, 0x2d, 0xd2, 0x7b, 0x93, 0x6b, 0xef, 0x2d, 0x80, 0x44, 0xf0,
+	0xb3, 0xdd, 0x52, 0x0a, 0x2d, 0x50, 0xdd, 0x7c, 0xdf, 0xbb, 0xbd, 0x10, 0x0b, 0x61, 0x81, 0x2f,
+	0xcc, 0x97, 0xe3, 0xa2, 0x3f, 0x1a, 0xd0, 0x3c, 0x12, 0xfc, 0x8c, 0x2d, 0xd0, 0x57, 0xd0, 0x54,
+	0x54, 0x5e, 0x50, 0x19, 0x06, 0xa3, 0xda, 0xb8, 0xbb, 0xd7, 0xdb, 0xb5, 0x67, 0x4c, 0x2d, 0x76,
+	0xb8, 0xf9, 0xee, 0xfd, 0xce, 0xad, 0xab, 0xf7, 0x3b, 0x2d, 0xb7, 0x56, 0xd8, 0x8b, 0xd1, 0x37,
+	0xd0, 0x93, 0x54, 0x89, 0xfc, 0x82, 0x92, 0x42, 0xa4, 0x34, 0xdc, 0x18, 0x05, 0xe3, 0xc1, 0x5e,
+	0xe8, 0x36, 0xbb, 0xa3, 0x77, 0xb1, 0x13, 0x9c, 0x8a, 0x94, 0xe2, 0xae, 0xfc, 0xb0, 0x40, 0x3b,
+	0xd0, 0xcd, 0x99, 0xd2, 0x94, 0x93, 0x38, 0x4d, 0x65, 0x58, 0x1b, 0x05, 0xe3, 0x0e, 0x06, 0x07,
+	0x1d, 0xa4, 0xa9, 0xb4, 0x02, 0xb1, 0x20, 0x6f, 0x96, 0x54, 0x32, 0xaa, 0xc2, 0xfa, 0x28, 0x18,
+	0xb7, 0x31, 0xe4, 0x62, 0xf1, 0xa3, 0x43, 0xd0, 0x7f, 0xa1, 0x2f, 0x2e, 0xa8, 0x94, 0x2c, 0xa5,
+	0xe4, 0x8c, 0xe5, 0x34, 0x6c, 0xd8, 0x33, 0x7a, 0x15, 0xf8, 0x8c, 0xe5, 0x14, 0xed, 0xc3, 0x5d,
+	0x49, 0x73, 0x11, 0xa7, 0x84, 0x71, 0x4d, 0xe5, 0x45, 0x9c, 0x13, 0x45, 0x13, 0xc1, 0x53, 0x15,
+	0x36, 0x47, 0xc1, 0xb8, 0x8f, 0xff, 0xed, 0xe8, 0x17, 0x9e, 0x9d, 0x3a, 0x12, 0x3d, 0x80, 0x46,
+	0x12, 0x27, 0x19, 0x0d, 0x5b, 0xa3, 0x60, 0xdc, 0xdd, 0xeb, 0xfa, 0xa4, 0x0c, 0x84, 0x1d, 0x63,
+	0x24, 0x71, 0x5a, 0x30, 0x1e, 0xb6, 0xaf, 0x4b, 0x0e, 0x0c, 0x84, 0x1d, 0x83, 0x9e, 0x40, 0xc7,
+	0xc4, 0xbf, 0x22, 0xb9, 0x58, 0x84, 0x1d, 0x2b, 0x1b, 0x38, 0x99, 0x49, 0x62, 0xf5, 0xbd, 0x58,
+	0xe0, 0xf6, 0x1b, 0xff, 0x85, 0x1e, 0x42, 0x33, 0xe5, 0x4a, 0xc7, 0x65, 0x08, 0xa3, 0xe0, 0xc3,
+	0x2b, 0x1c, 0x5b, 0x0c, 0x7b, 0x0e, 0x3d, 0x82, 0x96, 0x96, 0x71, 0xc2, 0xf8, 0x22, 0xec, 0x5a,
+	0x59, 0xdf, 0xc9, 0x66, 0x0e, 0xc4, 0x15, 0xeb, 0x8f, 0x53, 0x34, 0x09, 0x7b, 0x37, 0x8e, 0x53,
+	0x34, 0xc1, 0x9e, 0x43, 0x47, 0xb0, 0x25, 0xe9, 0x9c, 0xf1, 0x94, 0x18, 0x57, 0xd0, 0x44, 0x33,
+	0xc1, 0xc3, 0xbe, 0xdd, 0x70, 0xc7, 0x6d, 0xc0, 0x96, 0x9e, 0xac, 0x59, 0x3c, 0x94, 0x37, 0x10,
+	0x74, 0x1f, 0x6a, 0x34, 0x51, 0xe1, 0xc0, 0x6e, 0xeb, 0xb8, 0x6d, 0x27, 0x89, 0xc2, 0x06, 0x45,
+	0x4f, 0x60, 0x6b, 0x2e, 0x84, 0x56, 0x5a, 0xc6, 0x25, 0x71, 0xce, 0x51, 0xe1, 0xe6, 0xa8, 0x36,
+	0xee, 0xe0, 0xe1, 0x9a, 0xf0, 0xd6, 0x8a, 0xf6, 0xa1, 0x7b, 0xcd, 0x31, 0x08, 0xa0, 0x89, 0x63,
+	0x9e, 0x8a, 0x62, 0x78, 0x0b, 0x75, 0xa1, 0xf5, 0x82, 0xbf, 0x92, 0x29, 0x95, 0xc3, 0x00, 0x0d,
+	0x00, 0x8e, 0x04, 0x4f, 0x96, 0x52, 0x52, 0xae, 0x87, 0x1b, 0xd1, 0x6f, 0x01, 0xd4, 0x4e, 0x12,
+	0x85, 0x22, 0xa8, 0x5b, 0x2b, 0x06, 0xd6, 0x8a, 0x83, 0x75, 0x28, 0xbb, 0xd6, 0x80, 0x96, 0x33,
+	0xc6, 0x62, 0xe5, 0xc5, 0x97, 0xa4, 0x94, 0xf4, 0x8c, 0x5d, 0x5a, 0xd7, 0xf6, 0x31, 0x18, 0x68,
+	0x62, 0x11, 0x2f, 0xd8, 0xaf, 0x04, 0xb5, 0xb5, 0x60, 0xdf, 0x0b, 0x42, 0x68, 0x19, 0xd3, 0x52,
+	0xe5, 0x6c, 0xd9, 0xc1, 0xd5, 0x32, 0x7a, 0x08, 0x75, 0x1b, 0x78, 0x07, 0x1a, 0xd3, 0x19, 0x7e,
+	0x31, 0x19, 0xde, 0x42, 0x2d, 0xa8, 0x1d, 0x1c, 0x1f, 0x0f, 0x03, 0xd4, 0x86, 0xfa, 0xe4, 0x60,
+	0x3a, 0x1d, 0x6e, 0x44, 0xbf, 0x07, 0x30, 0xbc, 0x79, 0xad, 0xa6, 0x08, 0x63, 0xfb, 0xe5, 0x83,
+	0xff, 0xcf, 0xa7, 0xaf, 0x7f, 0xf7, 0xc0, 0xfe, 0xc1, 0x5e, 0x6c, 0xaa, 0x60, 0x9e, 0x8b, 0xe4,
+	0x9c, 0xa6, 0x24, 0x61, 0xa9, 0x54, 0xe1, 0x86, 0xbd, 0xda, 0x9e, 0x07, 0x8f, 0x0c, 0x86, 0x1e,
+	0xc1, 0x66, 0x9c, 0xe7, 0xe2, 0x27, 0x9a, 0x92, 0x54, 0x14, 0x31, 0xe3, 0x2a, 0xac, 0x59, 0xd9,
+	0xc0, 0xc3, 0xc7, 0x0e, 0x8d, 0x76, 0xa0, 0xe9, 0xce, 0xbf, 0x9e, 0x81, 0x79, 0x85, 0x93, 0x67,
+	0xaf, 0xa7, 0x27, 0xc3, 0x20, 0xfa, 0x1c, 0x9a, 0xce, 0x41, 0xe6, 0x1f, 0x6b, 0xb9, 0x54, 0x9a,
+	0xc4, 0x3c, 0xc9, 0x84, 0x54, 0xb6, 0x77, 0x74, 0x70, 0xcf, 0x82, 0x07, 0x0e, 0x8b, 0x7e, 0x0e,
+	0xa0, 0xe5, 0x9d, 0x89, 0xee, 0x41, 0x9b, 0xf2, 0xb4, 0x14, 0x8c, 0x6b, 0x9b, 0x62, 0x07, 0xaf,
+	0xd7, 0x86, 0x63, 0x5c, 0xd1, 0x64, 0x29, 0x5d, 0x1b, 0x69, 0xe3, 0xf5, 0x1a, 0x3d, 0x80, 0x9e,
+	0xb1, 0x0d, 0x4b, 0x28, 0xe1, 0x71, 0x41, 0x7d, 0xab, 0xe8, 0x7a, 0xec, 0x87, 0xb8, 0xa0, 0xe8,
+	0x7f, 0x30, 0x50, 0x71, 0x51, 0xe6, 0x94, 0x94, 0x54, 0x26, 0x94, 0x6b, 0xfb, 0x2e, 0x7d, 0xdc,
+	0x77, 0xe8, 0xc4, 0x81, 0xd1, 0xdc, 0x06, 0x6f, 0xaa, 0x68, 0x07, 0xba, 0xca, 0xdc, 0x8f, 0x26,
+	0x65, 0xac, 0x33, 0x1f, 0x0e, 0x38, 0x68, 0x12, 0xeb, 0x0c, 0xdd, 0x87, 0x8e, 0xe9, 0x29, 0x8e,
+	0xde, 0x70, 0xd1, 0x1a, 0xc0, 0x92, 0x26, 0xda, 0x94, 0x72, 0xcd, 0xf4, 0xca, 0x47, 0xb3, 0x5e,
+	0x47, 0xef, 0x03, 0x68, 0x57, 0xc5, 0x8d, 0x10, 0xd4, 0xaf, 0x9d, 0x6f, 0xbf, 0xd1, 0x36, 0x74,
+	0x8b, 0xf8, 0x92, 0x28, 0xf6, 0x96, 0x92, 0x62, 0xee, 0xed, 0xd7, 0x29, 0xe2, 0xcb, 0x29, 0x7b,
+	0x4b, 0x4f, 0xe7, 0x28, 0x82, 0xbe, 0xe1, 0xe3, 0x05, 0x25, 0x99, 0x58, 0x4a, 0xe5, 0xfd, 0x67,
+	0x36, 0x1d, 0x2c, 0xe8, 0x73, 0x03, 0x99, 0xf0, 0x8d, 0x66, 0x1e, 0x27, 0xe7, 0xcb, 0x52, 0xf9,
+	0x64, 0xa1, 0x88, 0x2f, 0x0f, 0x1d, 0x62, 0x22, 0x4c, 0x44, 0x51, 0x5a, 0x8b, 0x36, 0xdc, 0x7d,
+	0x56, 0x6b, 0x73, 0x59, 0xf3, 0xe5, 0xd9, 0x19, 0x95, 0x84, 0x72, 0x6d, 0x7b, 0xab, 0xeb, 0x84,
+	0x7d, 0x87, 0x9e, 0x38, 0x10, 0xdd, 0x81, 0xe6, 0x19, 0xa3, 0x79, 0xaa, 0xc2, 0x96, 0x7d, 0x58,
+	0xbf, 0x8a, 0x7e, 0xad, 0x41, 0xc3, 0xf6, 0xc1, 0x2a, 0x8a, 0xea, 0x94, 0x60, 0x1d, 0x45, 0x75,
+	0x84, 0xb9, 0x65, 0x53, 0xd8, 0x44, 0xe9, 0x38, 0xaf, 0x1e, 0x16, 0x2c, 0x34, 0x35, 0x08, 0x7a,
+	0x0c, 0x5b, 0x96, 0x22, 0x5a, 0x7f, 0xe8, 0xcb, 0x2e, 0xdf, 0x4d, 0x4b, 0xcc, 0xf4, 0xba, 0x23,
+	0x3f, 0x86, 0x2d, 0x7b, 0x6f, 0x56, 0x5f, 0x69, 0x5d, 0xe6, 0x9b, 0xe6, 0xf6, 0x0c, 0x7e, 0x4d,
+	0x9b, 0xe4, 0x8c, 0x72, 0x4d, 0x34, 0x2b, 0xa8, 0x58, 0x6a, 0x52, 0xb8, 0x7b, 0xe8, 0xe3, 0x4d,
+	0x47, 0xcc, 0x1c, 0x7e, 0x6a, 0xb5, 0xa6, 0xd0, 0xa9, 0x4e, 0x32, 0x52, 0x30, 0x4e, 0x32, 0xa6,
+	0xab, 0x1b, 0xd9, 0xac, 0x88, 0x53, 0xc6, 0x9f, 0x33, 0xad, 0xd0, 0xb7, 0x70, 0x6f, 0xad, 0xd5,
+	0x99, 0xa4, 0x2a, 0x13, 0x79, 0xba, 0xf6, 0x5c, 0xcb, 0x6e, 0x0a, 0x2b, 0xc5, 0xac, 0x12, 0x78,
+	0xfb, 0x19, 0x23, 0x97, 0x54, 0x2a, 0xa6, 0xb4, 0x9b, 0x57, 0x6d, 0x67, 0x64, 0x8f, 0xd9, 0x71,
+	0xf5, 0x35, 0x84, 0x95, 0xe4, 0xa3, 0x79, 0xd5, 0xb1, 0xc7, 0xdf, 0xf1, 0xfc, 0x8d, 0x81, 0x15,
+	0xbd, 0x84, 0x86, 0x1d, 0x3d, 0x37, 0x07, 0x6b, 0xf0, 0xd1, 0x60, 0x7d, 0x00, 0xbd, 0x39, 0x8d,
+	0x25, 0x95, 0x44, 0x8b, 0x73, 0xca, 0xbd, 0xbb, 0xbb, 0x0e, 0x9b, 0x19, 0x28, 0xfa, 0xa5, 0x0e,
+	0x4d, 0xd7, 0x92, 0x8d, 0x85, 0x6d, 0xd5, 0x79, 0x0b, 0x73, 0x57, 0x6e, 0x75, 0xbd, 0x2a, 0xab,
+	0x81, 0xbf, 0x75, 0xfd, 0xd7, 0xc2, 0xee, 0x6c, 0x55, 0x52, 0x6c, 0x69, 0x53, 0x43, 0x99, 0x50,
+	0x9a, 0x94, 0x42, 0x6a, 0xdf, 0x6f, 0xda, 0x06, 0x98, 0x08, 0xa9, 0xd1, 0x5d, 0x68, 0xa5, 0x22,
+	0x23, 0x4b, 0x99, 0xfb, 0x1e, 0xda, 0x4c, 0x45, 0xf6, 0x5a, 0xe6, 0xd5, 0x30, 0x69, 0x7c, 0x72,
+	0x98, 0x7c, 0x06, 0xa8, 0x8c, 0xd3, 0x94, 0xf1, 0x05, 0xb1, 0x0d, 0xce, 0x96, 0x91, 0x7f, 0xad,
+	0xa1, 0x67, 0x0e, 0x0d, 0x61, 0x8a, 0xc9, 0xb4, 0xbd, 0x94, 0xa9, 0x78, 0x6e, 0xeb, 0xd8, 0x72,
+	0xf6, 0x8d, 0xda, 0x78, 0xe0, 0xe1, 0x89, 0x43, 0xd1, 0x1e, 0x80, 0x09, 0xa6, 0xa0, 0x3a, 0x13,
+	0xa9, 0x7d, 0x97, 0xc1, 0xde, 0xbf, 0xfe, 0x91, 0xd6, 0xa9, 0xa5, 0x70, 0x27, 0x15, 0x99, 0xfb,
+	0x44, 0xb7, 0xa1, 0x91, 0x69, 0x5d, 0x3e, 0xb5, 0xef, 0xd2, 0xc6, 0x6e, 0x61, 0x72, 0x56, 0xe5,
+	0x39, 0x23, 0xa5, 0xe9, 0xb1, 0xe0, 0x72, 0x36, 0xc0, 0x84, 0x71, 0x65, 0x72, 0x4e, 0x62, 0xf7,
+	0xf6, 0x5d, 0x97, 0x73, 0x12, 0xdb, 0x67, 0x1f, 0xc3, 0xd0, 0xfb, 0x35, 0xa1, 0xd2, 0xbb, 0xa3,
+	0x67, 0x15, 0x03, 0x87, 0x1f, 0x51, 0xe9, 0x0c, 0xf2, 0x7f, 0xf0, 0x06, 0x26, 0xe7, 0x74, 0xe5,
+	0x84, 0x7d, 0x2b, 0xec, 0x3b, 0xf8, 0x25, 0x5d, 0x59, 0xdd, 0x6d, 0x68, 0x94, 0x52, 0x5c, 0xae,
+	0xec, 0x50, 0xee, 0x60, 0xb7, 0x88, 0x42, 0xa8, 0x9b, 0xf7, 0x31, 0x33, 0xe9, 0xf5, 0xb1, 0x1f,
+	0x4e, 0xc7, 0xaf, 0x9e, 0x0f, 0x83, 0xe8, 0x3e, 0x34, 0x7d, 0x5e, 0x66, 0x4c, 0xbd, 0x9a, 0xce,
+	0x1c, 0xf9, 0xdd, 0xc9, 0x6c, 0x18, 0x1c, 0xf6, 0xde, 0x5d, 0x6d, 0x07, 0x7f, 0x5e, 0x6d, 0x07,
+	0x7f, 0x5d, 0x6d, 0x07, 0xf3, 0xa6, 0xfd, 0xfd, 0xf8, 0xf4, 0xef, 0x01, 0x00, 0x4d, 0x24, 0xfc,
+	0x05, 0x69, 0x0a, 0x00, 0x00,
+}
+
+func (m *Config) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Config) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Config) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.BootstrapServers) > 0 {
+		for iNdEx := len(m.BootstrapServers) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.BootstrapServers[iNdEx])
+			copy(dAtA[i:], m.BootstrapServers[iNdEx])
+			i = encodeVarintConf(dAtA, i, uint64(len(m.BootstrapServers[iNdEx])))
+			i--
+			dAtA[i] = 0x7a
+		}
+	}
+	if m.Ecs != nil {
+		{
+			size, err := m.Ecs.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintConf(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x72
+	}
+	if m.RebindProtection != nil {
+		{
+			size, err := m.RebindProtection.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintConf(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x6a
+	}
+	if m.Dnssec != nil {
+		{
+			size, err := m.Dnssec.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintConf(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x62
+	}
+	if m.Tracing != nil {
+		{
+			size, err := m.Tracing.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintConf(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x5a
+	}
+	if m.Dnstap != nil {
+		{
+			size, err := m.Dnstap.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintConf(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x52
+	}
+	if m.QueryLog != nil {
+		{
+			size, err := m.QueryLog.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintConf(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x4a
+	}
+	if m.Admin != nil {
+		{
+			size, err := m.Admin.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintConf(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x42
+	}
+	if m.Cache != nil {
+		{
+			size, err := m.Cache.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintConf(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x3a
+	}
+	if m.ReloadIntervalSeconds != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.ReloadIntervalSeconds))
+		i--
+		dAtA[i] = 0x30
+	}
+	if len(m.OverrideFile) > 0 {
+		i -= len(m.OverrideFile)
+		copy(dAtA[i:], m.OverrideFile)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.OverrideFile)))
+		i--
+		dAtA[i] = 0x2a
+	}
+	if m.LogQueries {
+		i--
+		if m.LogQueries {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x20
+	}
+	if len(m.ListenAddr) > 0 {
+		i -= len(m.ListenAddr)
+		copy(dAtA[i:], m.ListenAddr)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.ListenAddr)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.ResolveMode != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.ResolveMode))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Servers) > 0 {
+		for iNdEx := len(m.Servers) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Servers[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintConf(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Ecs) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Ecs) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Ecs) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Address) > 0 {
+		i -= len(m.Address)
+		copy(dAtA[i:], m.Address)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.Address)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.Ipv6Prefix != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.Ipv6Prefix))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Ipv4Prefix != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.Ipv4Prefix))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Mode != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.Mode))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *RebindProtection) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *RebindProtection) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *RebindProtection) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.AllowedDomains) > 0 {
+		for iNdEx := len(m.AllowedDomains) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.AllowedDomains[iNdEx])
+			copy(dAtA[i:], m.AllowedDomains[iNdEx])
+			i = encodeVarintConf(dAtA, i, uint64(len(m.AllowedDomains[iNdEx])))
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if len(m.BlockedCidrs) > 0 {
+		for iNdEx := len(m.BlockedCidrs) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.BlockedCidrs[iNdEx])
+			copy(dAtA[i:], m.BlockedCidrs[iNdEx])
+			i = encodeVarintConf(dAtA, i, uint64(len(m.BlockedCidrs[iNdEx])))
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if m.Action != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.Action))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Dnssec) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Dnssec) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Dnssec) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.TrustAnchors) > 0 {
+		for iNdEx := len(m.TrustAnchors) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.TrustAnchors[iNdEx])
+			copy(dAtA[i:], m.TrustAnchors[iNdEx])
+			i = encodeVarintConf(dAtA, i, uint64(len(m.TrustAnchors[iNdEx])))
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Tracing) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Tracing) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Tracing) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.SamplePercent != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.SamplePercent))
+		i--
+		dAtA[i] = 0x20
+	}
+	if len(m.ServiceName) > 0 {
+		i -= len(m.ServiceName)
+		copy(dAtA[i:], m.ServiceName)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.ServiceName)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.Insecure {
+		i--
+		if m.Insecure {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Endpoint) > 0 {
+		i -= len(m.Endpoint)
+		copy(dAtA[i:], m.Endpoint)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.Endpoint)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Dnstap) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Dnstap) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Dnstap) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Identity) > 0 {
+		i -= len(m.Identity)
+		copy(dAtA[i:], m.Identity)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.Identity)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if len(m.FilePath) > 0 {
+		i -= len(m.FilePath)
+		copy(dAtA[i:], m.FilePath)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.FilePath)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.SocketPath) > 0 {
+		i -= len(m.SocketPath)
+		copy(dAtA[i:], m.SocketPath)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.SocketPath)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *QueryLog) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *QueryLog) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *QueryLog) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Fields) > 0 {
+		for iNdEx := len(m.Fields) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.Fields[iNdEx])
+			copy(dAtA[i:], m.Fields[iNdEx])
+			i = encodeVarintConf(dAtA, i, uint64(len(m.Fields[iNdEx])))
+			i--
+			dAtA[i] = 0x3a
+		}
+	}
+	if m.BufferEntries != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.BufferEntries))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.Compress {
+		i--
+		if m.Compress {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.MaxBackups != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.MaxBackups))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.MaxAgeHours != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.MaxAgeHours))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.MaxSizeMb != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.MaxSizeMb))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Path) > 0 {
+		i -= len(m.Path)
+		copy(dAtA[i:], m.Path)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.Path)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Cache) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Cache) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Cache) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.PersistIntervalSeconds != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.PersistIntervalSeconds))
+		i--
+		dAtA[i] = 0x48
+	}
+	if len(m.PersistFile) > 0 {
+		i -= len(m.PersistFile)
+		copy(dAtA[i:], m.PersistFile)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.PersistFile)))
+		i--
+		dAtA[i] = 0x42
+	}
+	if m.PrefetchThresholdPercent != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.PrefetchThresholdPercent))
+		i--
+		dAtA[i] = 0x38
+	}
+	if m.PrefetchMinHits != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.PrefetchMinHits))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.ClientTimeoutMs != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.ClientTimeoutMs))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.MaxStaleSeconds != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.MaxStaleSeconds))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.StaleTtlSeconds != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.StaleTtlSeconds))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.ServeStale {
+		i--
+		if m.ServeStale {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.MaxEntries != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.MaxEntries))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Admin) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Admin) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Admin) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.BearerToken) > 0 {
+		i -= len(m.BearerToken)
+		copy(dAtA[i:], m.BearerToken)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.BearerToken)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.ListenAddr) > 0 {
+		i -= len(m.ListenAddr)
+		copy(dAtA[i:], m.ListenAddr)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.ListenAddr)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Server) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Server) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Server) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if len(m.Proxy) > 0 {
+		i -= len(m.Proxy)
+		copy(dAtA[i:], m.Proxy)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.Proxy)))
+		i--
+		dAtA[i] = 0x72
+	}
+	if len(m.ClientKeyFile) > 0 {
+		i -= len(m.ClientKeyFile)
+		copy(dAtA[i:], m.ClientKeyFile)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.ClientKeyFile)))
+		i--
+		dAtA[i] = 0x6a
+	}
+	if len(m.ClientCertFile) > 0 {
+		i -= len(m.ClientCertFile)
+		copy(dAtA[i:], m.ClientCertFile)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.ClientCertFile)))
+		i--
+		dAtA[i] = 0x62
+	}
+	if len(m.CaFile) > 0 {
+		i -= len(m.CaFile)
+		copy(dAtA[i:], m.CaFile)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.CaFile)))
+		i--
+		dAtA[i] = 0x5a
+	}
+	if len(m.SpkiPins) > 0 {
+		for iNdEx := len(m.SpkiPins) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.SpkiPins[iNdEx])
+			copy(dAtA[i:], m.SpkiPins[iNdEx])
+			i = encodeVarintConf(dAtA, i, uint64(len(m.SpkiPins[iNdEx])))
+			i--
+			dAtA[i] = 0x52
+		}
+	}
+	if m.Http3 {
+		i--
+		if m.Http3 {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x48
+	}
+	if m.DohMethod != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.DohMethod))
+		i--
+		dAtA[i] = 0x40
+	}
+	if m.DisablePadding {
+		i--
+		if m.DisablePadding {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x38
+	}
+	if m.PaddingBlockSize != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.PaddingBlockSize))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.Ecs != nil {
+		{
+			size, err := m.Ecs.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintConf(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x2a
+	}
+	if len(m.DohUrl) > 0 {
+		i -= len(m.DohUrl)
+		copy(dAtA[i:], m.DohUrl)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.DohUrl)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if len(m.HostPort) > 0 {
+		for iNdEx := len(m.HostPort) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.HostPort[iNdEx])
+			copy(dAtA[i:], m.HostPort[iNdEx])
+			i = encodeVarintConf(dAtA, i, uint64(len(m.HostPort[iNdEx])))
+			i--
+			dAtA[i] = 0x1a
+		}
+	}
+	if m.Type != 0 {
+		i = encodeVarintConf(dAtA, i, uint64(m.Type))
+		i--
+		dAtA[i] = 0x10
+	}
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintConf(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintConf(dAtA []byte, offset int, v uint64) int {
+	offset -= sovConf(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *Config) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Servers) > 0 {
+		for _, e := range m.Servers {
+			l = e.Size()
+			n += 1 + l + sovConf(uint64(l))
+		}
+	}
+	if m.ResolveMode != 0 {
+		n += 1 + sovConf(uint64(m.ResolveMode))
+	}
+	l = len(m.ListenAddr)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.LogQueries {
+		n += 2
+	}
+	l = len(m.OverrideFile)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.ReloadIntervalSeconds != 0 {
+		n += 1 + sovConf(uint64(m.ReloadIntervalSeconds))
+	}
+	if m.Cache != nil {
+		l = m.Cache.Size()
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.Admin != nil {
+		l = m.Admin.Size()
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.QueryLog != nil {
+		l = m.QueryLog.Size()
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.Dnstap != nil {
+		l = m.Dnstap.Size()
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.Tracing != nil {
+		l = m.Tracing.Size()
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.Dnssec != nil {
+		l = m.Dnssec.Size()
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.RebindProtection != nil {
+		l = m.RebindProtection.Size()
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.Ecs != nil {
+		l = m.Ecs.Size()
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if len(m.BootstrapServers) > 0 {
+		for _, s := range m.BootstrapServers {
+			l = len(s)
+			n += 1 + l + sovConf(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Ecs) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Mode != 0 {
+		n += 1 + sovConf(uint64(m.Mode))
+	}
+	if m.Ipv4Prefix != 0 {
+		n += 1 + sovConf(uint64(m.Ipv4Prefix))
+	}
+	if m.Ipv6Prefix != 0 {
+		n += 1 + sovConf(uint64(m.Ipv6Prefix))
+	}
+	l = len(m.Address)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *RebindProtection) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Action != 0 {
+		n += 1 + sovConf(uint64(m.Action))
+	}
+	if len(m.BlockedCidrs) > 0 {
+		for _, s := range m.BlockedCidrs {
+			l = len(s)
+			n += 1 + l + sovConf(uint64(l))
+		}
+	}
+	if len(m.AllowedDomains) > 0 {
+		for _, s := range m.AllowedDomains {
+			l = len(s)
+			n += 1 + l + sovConf(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Dnssec) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.TrustAnchors) > 0 {
+		for _, s := range m.TrustAnchors {
+			l = len(s)
+			n += 1 + l + sovConf(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Tracing) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Endpoint)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.Insecure {
+		n += 2
+	}
+	l = len(m.ServiceName)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.SamplePercent != 0 {
+		n += 1 + sovConf(uint64(m.SamplePercent))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Dnstap) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.SocketPath)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	l = len(m.FilePath)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	l = len(m.Identity)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *QueryLog) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Path)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.MaxSizeMb != 0 {
+		n += 1 + sovConf(uint64(m.MaxSizeMb))
+	}
+	if m.MaxAgeHours != 0 {
+		n += 1 + sovConf(uint64(m.MaxAgeHours))
+	}
+	if m.MaxBackups != 0 {
+		n += 1 + sovConf(uint64(m.MaxBackups))
+	}
+	if m.Compress {
+		n += 2
+	}
+	if m.BufferEntries != 0 {
+		n += 1 + sovConf(uint64(m.BufferEntries))
+	}
+	if len(m.Fields) > 0 {
+		for _, s := range m.Fields {
+			l = len(s)
+			n += 1 + l + sovConf(uint64(l))
+		}
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Cache) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.MaxEntries != 0 {
+		n += 1 + sovConf(uint64(m.MaxEntries))
+	}
+	if m.ServeStale {
+		n += 2
+	}
+	if m.StaleTtlSeconds != 0 {
+		n += 1 + sovConf(uint64(m.StaleTtlSeconds))
+	}
+	if m.MaxStaleSeconds != 0 {
+		n += 1 + sovConf(uint64(m.MaxStaleSeconds))
+	}
+	if m.ClientTimeoutMs != 0 {
+		n += 1 + sovConf(uint64(m.ClientTimeoutMs))
+	}
+	if m.PrefetchMinHits != 0 {
+		n += 1 + sovConf(uint64(m.PrefetchMinHits))
+	}
+	if m.PrefetchThresholdPercent != 0 {
+		n += 1 + sovConf(uint64(m.PrefetchThresholdPercent))
+	}
+	l = len(m.PersistFile)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.PersistIntervalSeconds != 0 {
+		n += 1 + sovConf(uint64(m.PersistIntervalSeconds))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Admin) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.ListenAddr)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	l = len(m.BearerToken)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Server) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.Type != 0 {
+		n += 1 + sovConf(uint64(m.Type))
+	}
+	if len(m.HostPort) > 0 {
+		for _, s := range m.HostPort {
+			l = len(s)
+			n += 1 + l + sovConf(uint64(l))
+		}
+	}
+	l = len(m.DohUrl)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.Ecs != nil {
+		l = m.Ecs.Size()
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.PaddingBlockSize != 0 {
+		n += 1 + sovConf(uint64(m.PaddingBlockSize))
+	}
+	if m.DisablePadding {
+		n += 2
+	}
+	if m.DohMethod != 0 {
+		n += 1 + sovConf(uint64(m.DohMethod))
+	}
+	if m.Http3 {
+		n += 2
+	}
+	if len(m.SpkiPins) > 0 {
+		for _, s := range m.SpkiPins {
+			l = len(s)
+			n += 1 + l + sovConf(uint64(l))
+		}
+	}
+	l = len(m.CaFile)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	l = len(m.ClientCertFile)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	l = len(m.ClientKeyFile)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	l = len(m.Proxy)
+	if l > 0 {
+		n += 1 + l + sovConf(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func sovConf(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozConf(x uint64) (n int) {
+	return sovConf(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *Config) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowConf
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Config: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Config: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Servers", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Servers = append(m.Servers, Server{})
+			if err := m.Servers[len(m.Servers)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ResolveMode", wireType)
+			}
+			m.ResolveMode = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ResolveMode |= Config_ResolveMode(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ListenAddr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ListenAddr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field LogQueries", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.LogQueries = bool(v != 0)
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field OverrideFile", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.OverrideFile = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ReloadIntervalSeconds", wireType)
+			}
+			m.ReloadIntervalSeconds = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ReloadIntervalSeconds |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Cache", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Cache == nil {
+				m.Cache = &Cache{}
+			}
+			if err := m.Cache.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Admin", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Admin == nil {
+				m.Admin = &Admin{}
+			}
+			if err := m.Admin.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueryLog", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.QueryLog == nil {
+				m.QueryLog = &QueryLog{}
+			}
+			if err := m.QueryLog.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Dnstap", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Dnstap == nil {
+				m.Dnstap = &Dnstap{}
+			}
+			if err := m.Dnstap.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 11:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Tracing", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Tracing == nil {
+				m.Tracing = &Tracing{}
+			}
+			if err := m.Tracing.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Dnssec", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Dnssec == nil {
+				m.Dnssec = &Dnssec{}
+			}
+			if err := m.Dnssec.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 13:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RebindProtection", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.RebindProtection == nil {
+				m.RebindProtection = &RebindProtection{}
+			}
+			if err := m.RebindProtection.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 14:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ecs", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Ecs == nil {
+				m.Ecs = &Ecs{}
+			}
+			if err := m.Ecs.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 15:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BootstrapServers", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.BootstrapServers = append(m.BootstrapServers, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipConf(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthConf
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Ecs) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowConf
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Ecs: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Ecs: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Mode", wireType)
+			}
+			m.Mode = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Mode |= Ecs_Mode(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ipv4Prefix", wireType)
+			}
+			m.Ipv4Prefix = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Ipv4Prefix |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Ipv6Prefix", wireType)
+			}
+			m.Ipv6Prefix = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Ipv6Prefix |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Address", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Address = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipConf(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthConf
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *RebindProtection) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowConf
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: RebindProtection: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: RebindProtection: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Action", wireType)
+			}
+			m.Action = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Action |= RebindProtection_Action(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BlockedCidrs", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.BlockedCidrs = append(m.BlockedCidrs, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AllowedDomains", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.AllowedDomains = append(m.AllowedDomains, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipConf(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthConf
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Dnssec) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowConf
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Dnssec: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Dnssec: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field TrustAnchors", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.TrustAnchors = append(m.TrustAnchors, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipConf(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthConf
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Tracing) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowConf
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Tracing: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Tracing: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Endpoint", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Endpoint = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Insecure", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Insecure = bool(v != 0)
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ServiceName", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ServiceName = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SamplePercent", wireType)
+			}
+			m.SamplePercent = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.SamplePercent |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipConf(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthConf
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Dnstap) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowConf
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Dnstap: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Dnstap: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SocketPath", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.SocketPath = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field FilePath", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.FilePath = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Identity", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Identity = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipConf(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthConf
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *QueryLog) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowConf
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: QueryLog: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: QueryLog: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Path", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Path = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxSizeMb", wireType)
+			}
+			m.MaxSizeMb = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxSizeMb |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxAgeHours", wireType)
+			}
+			m.MaxAgeHours = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxAgeHours |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxBackups", wireType)
+			}
+			m.MaxBackups = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxBackups |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compress", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Compress = bool(v != 0)
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BufferEntries", wireType)
+			}
+			m.BufferEntries = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.BufferEntries |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Fields", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Fields = append(m.Fields, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipConf(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthConf
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Cache) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowConf
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Cache: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Cache: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxEntries", wireType)
+			}
+			m.MaxEntries = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxEntries |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ServeStale", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ServeStale = bool(v != 0)
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field StaleTtlSeconds", wireType)
+			}
+			m.StaleTtlSeconds = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.StaleTtlSeconds |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxStaleSeconds", wireType)
+			}
+			m.MaxStaleSeconds = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxStaleSeconds |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ClientTimeoutMs", wireType)
+			}
+			m.ClientTimeoutMs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ClientTimeoutMs |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PrefetchMinHits", wireType)
+			}
+			m.PrefetchMinHits = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.PrefetchMinHits |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PrefetchThresholdPercent", wireType)
+			}
+			m.PrefetchThresholdPercent = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.PrefetchThresholdPercent |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PersistFile", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.PersistFile = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PersistIntervalSeconds", wireType)
+			}
+			m.PersistIntervalSeconds = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.PersistIntervalSeconds |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipConf(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthConf
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Admin) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowConf
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Admin: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Admin: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ListenAddr", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ListenAddr = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BearerToken", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.BearerToken = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipConf(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthConf
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
 	}
-	return n
-}
 
-func sovConf(x uint64) (n int) {
-	return (math_bits.Len64(x|1) + 6) / 7
-}
-func sozConf(x uint64) (n int) {
-	return sovConf(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
 }
-func (m *Config) Unmarshal(dAtA []byte) error {
+func (m *Server) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
 	for iNdEx < l {
@@ -485,17 +4094,17 @@ func (m *Config) Unmarshal(dAtA []byte) error {
 		fieldNum := int32(wire >> 3)
 		wireType := int(wire & 0x7)
 		if wireType == 4 {
-			return fmt.Errorf("proto: Config: wiretype end group for non-group")
+			return fmt.Errorf("proto: Server: wiretype end group for non-group")
 		}
 		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Config: illegal tag %d (wire type %d)", fieldNum, wire)
+			return fmt.Errorf("proto: Server: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
 		case 1:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Servers", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
 			}
-			var msglen int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowConf
@@ -505,31 +4114,29 @@ func (m *Config) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			if msglen < 0 {
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
 				return ErrInvalidLengthConf
 			}
-			postIndex := iNdEx + msglen
+			postIndex := iNdEx + intStringLen
 			if postIndex < 0 {
 				return ErrInvalidLengthConf
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Servers = append(m.Servers, Server{})
-			if err := m.Servers[len(m.Servers)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
-				return err
-			}
+			m.Name = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		case 2:
 			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ResolveMode", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
 			}
-			m.ResolveMode = 0
+			m.Type = 0
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowConf
@@ -539,14 +4146,14 @@ func (m *Config) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.ResolveMode |= Config_ResolveMode(b&0x7F) << shift
+				m.Type |= Server_Type(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
 		case 3:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field ListenAddr", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field HostPort", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -574,13 +4181,13 @@ func (m *Config) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.ListenAddr = string(dAtA[iNdEx:postIndex])
+			m.HostPort = append(m.HostPort, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
 		case 4:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field LogQueries", wireType)
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DohUrl", wireType)
 			}
-			var v int
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowConf
@@ -590,17 +4197,29 @@ func (m *Config) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= int(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			m.LogQueries = bool(v != 0)
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.DohUrl = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 5:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field OverrideFile", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field Ecs", wireType)
 			}
-			var stringLen uint64
+			var msglen int
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowConf
@@ -610,81 +4229,109 @@ func (m *Config) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				stringLen |= uint64(b&0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-			intStringLen := int(stringLen)
-			if intStringLen < 0 {
+			if msglen < 0 {
 				return ErrInvalidLengthConf
 			}
-			postIndex := iNdEx + intStringLen
+			postIndex := iNdEx + msglen
 			if postIndex < 0 {
 				return ErrInvalidLengthConf
 			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.OverrideFile = string(dAtA[iNdEx:postIndex])
-			iNdEx = postIndex
-		default:
-			iNdEx = preIndex
-			skippy, err := skipConf(dAtA[iNdEx:])
-			if err != nil {
+			if m.Ecs == nil {
+				m.Ecs = &Ecs{}
+			}
+			if err := m.Ecs.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
 				return err
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthConf
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PaddingBlockSize", wireType)
 			}
-			if (iNdEx + skippy) < 0 {
-				return ErrInvalidLengthConf
+			m.PaddingBlockSize = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.PaddingBlockSize |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			if (iNdEx + skippy) > l {
-				return io.ErrUnexpectedEOF
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DisablePadding", wireType)
 			}
-			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
-			iNdEx += skippy
-		}
-	}
-
-	if iNdEx > l {
-		return io.ErrUnexpectedEOF
-	}
-	return nil
-}
-func (m *Server) Unmarshal(dAtA []byte) error {
-	l := len(dAtA)
-	iNdEx := 0
-	for iNdEx < l {
-		preIndex := iNdEx
-		var wire uint64
-		for shift := uint(0); ; shift += 7 {
-			if shift >= 64 {
-				return ErrIntOverflowConf
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-			if iNdEx >= l {
-				return io.ErrUnexpectedEOF
+			m.DisablePadding = bool(v != 0)
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DohMethod", wireType)
 			}
-			b := dAtA[iNdEx]
-			iNdEx++
-			wire |= uint64(b&0x7F) << shift
-			if b < 0x80 {
-				break
+			m.DohMethod = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.DohMethod |= Server_Method(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
 			}
-		}
-		fieldNum := int32(wire >> 3)
-		wireType := int(wire & 0x7)
-		if wireType == 4 {
-			return fmt.Errorf("proto: Server: wiretype end group for non-group")
-		}
-		if fieldNum <= 0 {
-			return fmt.Errorf("proto: Server: illegal tag %d (wire type %d)", fieldNum, wire)
-		}
-		switch fieldNum {
-		case 1:
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Http3", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Http3 = bool(v != 0)
+		case 10:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field SpkiPins", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -712,13 +4359,13 @@ func (m *Server) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.Name = string(dAtA[iNdEx:postIndex])
+			m.SpkiPins = append(m.SpkiPins, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
-		case 2:
-			if wireType != 0 {
-				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+		case 11:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field CaFile", wireType)
 			}
-			m.Type = 0
+			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
 				if shift >= 64 {
 					return ErrIntOverflowConf
@@ -728,14 +4375,27 @@ func (m *Server) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				m.Type |= Server_Type(b&0x7F) << shift
+				stringLen |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
 			}
-		case 3:
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.CaFile = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 12:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field HostPort", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ClientCertFile", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -763,11 +4423,11 @@ func (m *Server) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.HostPort = string(dAtA[iNdEx:postIndex])
+			m.ClientCertFile = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
-		case 4:
+		case 13:
 			if wireType != 2 {
-				return fmt.Errorf("proto: wrong wireType = %d for field DohUrl", wireType)
+				return fmt.Errorf("proto: wrong wireType = %d for field ClientKeyFile", wireType)
 			}
 			var stringLen uint64
 			for shift := uint(0); ; shift += 7 {
@@ -795,7 +4455,39 @@ func (m *Server) Unmarshal(dAtA []byte) error {
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
-			m.DohUrl = string(dAtA[iNdEx:postIndex])
+			m.ClientKeyFile = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 14:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Proxy", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowConf
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthConf
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthConf
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Proxy = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
@@ -803,10 +4495,7 @@ func (m *Server) Unmarshal(dAtA []byte) error {
 			if err != nil {
 				return err
 			}
-			if skippy < 0 {
-				return ErrInvalidLengthConf
-			}
-			if (iNdEx + skippy) < 0 {
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
 				return ErrInvalidLengthConf
 			}
 			if (iNdEx + skippy) > l {
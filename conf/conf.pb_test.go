@@ -34,6 +34,7 @@ server: {
   name: "google"
   type: DOH
   host_port: "8.8.8.8:443"
+  host_port: "[2001:4860:4860::8888]:443"
   doh_url: "https://dns.google/dns-query"
 }
 server: {
@@ -49,33 +50,33 @@ func TestConf(t *testing.T) {
 		{
 			Name:     "google",
 			Type:     Server_UDP,
-			HostPort: "8.8.8.8:53",
+			HostPort: []string{"8.8.8.8:53"},
 		},
 		{
 			Name:     "cloudflare",
 			Type:     Server_UDP,
-			HostPort: "1.1.1.1:53",
+			HostPort: []string{"1.1.1.1:53"},
 		},
 		{
 			Name:     "sonic",
 			Type:     Server_UDP,
-			HostPort: "208.201.224.11:53",
+			HostPort: []string{"208.201.224.11:53"},
 		},
 		{
 			Name:     "sonic",
 			Type:     Server_UDP,
-			HostPort: "208.201.224.33:53",
+			HostPort: []string{"208.201.224.33:53"},
 		},
 		{
 			Name:     "google",
 			Type:     Server_DOH,
-			HostPort: "8.8.8.8:443",
+			HostPort: []string{"8.8.8.8:443", "[2001:4860:4860::8888]:443"},
 			DohUrl:   "https://dns.google/dns-query",
 		},
 		{
 			Name:     "cloudflare",
 			Type:     Server_DOH,
-			HostPort: "1.1.1.1:443",
+			HostPort: []string{"1.1.1.1:443"},
 			DohUrl:   "https://cloudflare-dns.com/dns-query",
 		},
 	}
@@ -4,32 +4,54 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"math/rand"
 	"net"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/miekg/dns"
 	"github.com/psanford/dnsforward/conf"
 	"github.com/psanford/dnsforward/doh"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var confFile = flag.String("conf", "dnsforward.conf", "Path to config file")
 
+// subcommands run instead of the server when named as the first argument.
+var subcommands = map[string]func(args []string){
+	"query":  queryCmd,
+	"bench":  benchCmd,
+	"report": reportCmd,
+	"check":  checkCmd,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			cmd(os.Args[2:])
+			return
+		}
+	}
+
 	flag.Parse()
 	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
 
 	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, os.Interrupt, os.Kill)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
 
 	config, err := conf.Load(*confFile)
 	if err != nil {
@@ -44,7 +66,7 @@ func main() {
 
 	if config.ListenAddr == "SOCKET_ACTIVATION" {
 		listeners, packetConns := activationConns()
-		if len(packetConns) == 0 && len(packetConns) == 0 {
+		if len(listeners) == 0 && len(packetConns) == 0 {
 			log.Fatalf("No socket provided running in SOCKET_ACTIVATION mode")
 		}
 
@@ -79,9 +101,6 @@ func main() {
 		}
 
 		log.Printf("running")
-
-		<-sigs
-		os.Exit(0)
 	} else {
 		serverTCP := &dns.Server{
 			Net:     "tcp",
@@ -95,54 +114,194 @@ func main() {
 			Addr:    config.ListenAddr,
 		}
 
-		panic(serverUDP.ListenAndServe())
+		go func() {
+			panic(serverUDP.ListenAndServe())
+		}()
 	}
+
+	if config.Admin != nil {
+		adminListener, err := listenAdmin(config.Admin)
+		if err != nil {
+			log.Fatalf("Admin listener error: %s", err)
+		}
+		log.Printf("admin api on: %s\n", adminListener.Addr())
+		go func() {
+			panic(s.serveAdmin(adminListener, config.Admin.BearerToken))
+		}()
+	}
+
+	<-sigs
+	s.shutdown()
+	os.Exit(0)
 }
 
 type server struct {
 	mux *dns.ServeMux
 
 	logStream      *json.Encoder
+	logFields      *logFieldSet
+	queryLog       *asyncWriter
 	nextID         uint32
 	clients        []*client
-	mode           conf.Config_ResolveMode
-	logQueries     bool
-	localOverrides map[string]string
+	backendsMu     sync.Mutex   // serializes enabling and disabling clients
+	mode           atomic.Int32 // conf.Config_ResolveMode
+	logQueries     atomic.Bool
+	localOverrides *overrides
+	inflight       inflight
+	cache          *cache
+	tap            *tap
+	stopTracing    func(context.Context) error
+	validator      *validator
+	rebind         *rebindFilter
+	done           chan struct{}
 }
 
-func newServer(config *conf.Config) *server {
+// newClients creates a client for every backend in config.
+func newClients(config *conf.Config) ([]*client, error) {
 	var clients []*client
+	names := make(map[string]bool)
 	for _, s := range config.Servers {
+		// Backends are looked up by name in the admin api and stats.
+		if names[s.Name] {
+			return nil, fmt.Errorf("server %q: duplicate name", s.Name)
+		}
+		names[s.Name] = true
+
+		var (
+			c   *client
+			err error
+		)
 		switch s.Type {
 		case conf.Server_UDP:
-			clients = append(clients, newClassicClient(s.Name, s.HostPort))
+			if len(s.HostPort) != 1 {
+				return nil, fmt.Errorf("server %q: UDP servers need exactly one host_port", s.Name)
+			}
+			var dial dialFunc
+			if s.Proxy != "" {
+				dial, err = newProxyDialer(s.Proxy, s.Name)
+				if err != nil {
+					return nil, fmt.Errorf("server %q: proxy: %w", s.Name, err)
+				}
+			}
+			c = newClassicClient(s.Name, s.HostPort[0], dial)
 		case conf.Server_DOH:
-			clients = append(clients, newDOHClient(s.DohUrl, s.HostPort))
+			var opts []doh.Option
+			opts, err = dohOptions(config, s)
+			if err == nil {
+				c, err = newDOHClient(s.Name, s.DohUrl, s.HostPort, opts...)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("server %q: %w", s.Name, err)
+			}
 		default:
-			log.Fatalf("Invalid server config: %+v", s)
+			return nil, fmt.Errorf("Invalid server config: %+v", s)
+		}
+
+		ecs := config.Ecs
+		if s.Ecs != nil {
+			ecs = s.Ecs
 		}
+		c.ecs, err = newECSPolicy(ecs)
+		if err != nil {
+			return nil, fmt.Errorf("server %q: ecs: %w", s.Name, err)
+		}
+
+		clients = append(clients, c)
 	}
 
 	if len(clients) < 1 {
-		log.Fatalf("No backend servers found in config")
+		return nil, errors.New("No backend servers found in config")
 	}
 
-	var overrides map[string]string
-	if config.OverrideFile != "" {
-		var err error
-		overrides, err = loadOverrides(config.OverrideFile)
+	return clients, nil
+}
+
+func newServer(config *conf.Config) *server {
+	clients, err := newClients(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	queryLog, err := newQueryLog(config.QueryLog)
+	if err != nil {
+		log.Fatalf("Failed to open query log: %s", err)
+	}
+
+	var logFields *logFieldSet
+	if config.QueryLog != nil {
+		logFields, err = newLogFieldSet(config.QueryLog.Fields)
 		if err != nil {
-			log.Fatalf("Failed to load local overrides: %s", err)
+			log.Fatal(err)
 		}
 	}
 
 	s := &server{
 		mux:            dns.NewServeMux(),
 		clients:        clients,
-		logStream:      json.NewEncoder(os.Stderr),
-		mode:           config.ResolveMode,
-		logQueries:     config.LogQueries,
-		localOverrides: overrides,
+		logStream:      json.NewEncoder(queryLog),
+		logFields:      logFields,
+		queryLog:       queryLog,
+		localOverrides: &overrides{},
+		done:           make(chan struct{}),
+	}
+	s.mode.Store(int32(config.ResolveMode))
+	s.logQueries.Store(config.LogQueries)
+
+	if config.Cache != nil {
+		s.cache = newCache(config.Cache)
+
+		if path := config.Cache.PersistFile; path != "" {
+			n, err := s.cache.load(path)
+			if err != nil && !os.IsNotExist(err) {
+				log.Printf("Failed to load cache from %s: %s", path, err)
+			} else if err == nil {
+				log.Printf("Loaded %d cache entries from %s", n, path)
+			}
+
+			interval := 5 * time.Minute
+			if config.Cache.PersistIntervalSeconds > 0 {
+				interval = time.Duration(config.Cache.PersistIntervalSeconds) * time.Second
+			}
+			go s.persistCache(interval)
+		}
+	}
+
+	if config.Tracing != nil {
+		s.stopTracing, err = setupTracing(config.Tracing)
+		if err != nil {
+			log.Fatalf("Failed to set up tracing: %s", err)
+		}
+	}
+
+	if config.Dnssec != nil {
+		s.validator, err = newValidator(config.Dnssec, s.dnssecLookup)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if config.RebindProtection != nil {
+		s.rebind, err = newRebindFilter(config.RebindProtection)
+		if err != nil {
+			log.Fatalf("rebind_protection: %s", err)
+		}
+	}
+
+	if config.Dnstap != nil {
+		s.tap, err = newTap(config.Dnstap)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if config.OverrideFile != "" {
+		reloadInterval := 5 * time.Second
+		if config.ReloadIntervalSeconds > 0 {
+			reloadInterval = time.Duration(config.ReloadIntervalSeconds) * time.Second
+		}
+		if err := s.watchOverrides(config.OverrideFile, reloadInterval); err != nil {
+			log.Fatalf("Failed to load local overrides: %s", err)
+		}
 	}
 
 	s.mux.HandleFunc(".", s.handleRequest)
@@ -150,49 +309,332 @@ func newServer(config *conf.Config) *server {
 	return s
 }
 
+// watchOverrides loads the override file at path and reloads it
+// whenever it changes until the server shuts down.
+//
+// The override file is the only file watched. It is data that is edited
+// while the server runs; the cache persist file is written by the server
+// itself, and ca_file and client certificates are read when the backends
+// are created, so like the rest of the config they take a restart.
+func (s *server) watchOverrides(path string, interval time.Duration) error {
+	watchFile(path, interval, s.done, func() {
+		names, err := loadOverrides(path)
+		if err != nil {
+			log.Printf("Failed to reload local overrides, keeping previous entries: %s", err)
+			return
+		}
+		s.localOverrides.replace(names)
+		log.Printf("Reloaded %d local overrides from %s", len(names), path)
+	})
+
+	names, err := loadOverrides(path)
+	if err != nil {
+		return err
+	}
+	s.localOverrides.replace(names)
+	return nil
+}
+
+// shutdown stops background work and saves any state that should
+// survive a restart.
+func (s *server) shutdown() {
+	close(s.done)
+
+	if s.cache != nil && s.cache.persistFile != "" {
+		if err := s.cache.save(s.cache.persistFile); err != nil {
+			log.Printf("Failed to save cache to %s: %s", s.cache.persistFile, err)
+		}
+	}
+
+	s.tap.close()
+
+	if s.stopTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := s.stopTracing(ctx); err != nil {
+			log.Printf("Failed to flush traces: %s", err)
+		}
+		cancel()
+	}
+
+	if err := s.queryLog.Close(); err != nil {
+		log.Printf("Failed to close query log: %s", err)
+	}
+}
+
+func (s *server) persistCache(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	path := s.cache.persistFile
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.cache.save(path); err != nil {
+				log.Printf("Failed to save cache to %s: %s", path, err)
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
 func (s *server) handleRequest(w dns.ResponseWriter, r *dns.Msg) {
+	id := s.newRequestID()
 	t0 := time.Now()
-	idI := atomic.AddUint32(&s.nextID, 1)
-	id := fmt.Sprintf("%d-%d", t0.Unix(), idI)
-	ctx := context.Background()
 
-	s.logRequest(id, r)
+	ctx := context.Background()
+	if ip, _ := addrIPPort(w.RemoteAddr()); ip != nil {
+		ctx = withClientIP(ctx, ip)
+	}
+	ctx, span := tracer.Start(ctx, "dns.query",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(questionAttributes(r)...),
+		trace.WithAttributes(
+			attribute.String("dns.id", id),
+			attribute.String("client.address", w.RemoteAddr().String()),
+			attribute.String("network.transport", clientTransport(w)),
+		))
+	defer span.End()
+
+	s.logRequest(id, w, r)
+	s.tap.clientQuery(w, r, t0)
 
 	resp := s.localOverrideResponse(r)
 	if resp != nil {
-		w.WriteMsg(resp)
+		setOutcome(ctx, "override")
+		s.writeResponse(ctx, id, w, r, resp, t0, true)
 		return
 	}
 
-	switch s.mode {
+	var stale *dns.Msg
+	if s.cache != nil {
+		cached, fresh, prefetch := s.cache.get(r, s.ecsScope(ctx, r))
+		if prefetch {
+			go s.prefetch(clientIP(ctx), r.Copy())
+		}
+		if cached != nil && fresh {
+			s.logCachedResult(id, false)
+			setOutcome(ctx, "cache")
+			s.writeResponse(ctx, id, w, r, cached, t0, false)
+			return
+		}
+		stale = cached
+	}
+
+	resp, err := s.forward(ctx, id, r, stale)
+	if err != nil {
+		setOutcome(ctx, "failure")
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+	if stale != nil && resp == stale {
+		setOutcome(ctx, "stale")
+	} else {
+		setOutcome(ctx, "backend")
+	}
+
+	s.writeResponse(ctx, id, w, r, resp, t0, false)
+}
+
+func (s *server) writeResponse(ctx context.Context, id string, w dns.ResponseWriter, r, resp *dns.Msg, queryTime time.Time, override bool) {
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("dns.rcode", dns.RcodeToString[resp.Rcode]))
+	w.WriteMsg(resp)
+	s.logResponse(id, w, r, resp, time.Since(queryTime), override)
+	s.tap.clientResponse(w, r, resp, queryTime)
+}
+
+func (s *server) newRequestID() string {
+	idI := atomic.AddUint32(&s.nextID, 1)
+	return fmt.Sprintf("%d-%d", time.Now().Unix(), idI)
+}
+
+// forward resolves r through the backends, sharing the upstream query
+// with any identical query already in flight. If stale is not nil it is
+// returned when the backends fail or don't answer within the cache's
+// client timeout; the upstream query keeps running in the background to
+// refresh the cache.
+func (s *server) forward(ctx context.Context, id string, r *dns.Msg, stale *dns.Msg) (*dns.Msg, error) {
+	t0 := time.Now()
+
+	type result struct {
+		resp *dns.Msg
+		err  error
+	}
+	scope := s.ecsScope(ctx, r)
+	key := coalesceKey(r, scope)
+	ch := make(chan result, 1)
+	go func() {
+		resp, leaderID, err := s.inflight.do(key, id, func() (*dns.Msg, error) {
+			return s.resolveAndCache(ctx, id, r)
+		})
+		if err == nil && leaderID != id {
+			// We piggybacked on another client's identical query; give this
+			// client its own copy with its message id.
+			resp = resp.Copy()
+			resp.Id = r.Id
+			s.logCoalesced(id, leaderID, time.Since(t0))
+			trace.SpanFromContext(ctx).SetAttributes(attribute.String("dns.coalesced_with", leaderID))
+		}
+		ch <- result{resp, err}
+	}()
+
+	if stale == nil {
+		res := <-ch
+		return res.resp, res.err
+	}
+
+	if s.cache.recentlyFailed(r, scope) {
+		s.logCachedResult(id, true)
+		return stale, nil
+	}
+
+	timer := time.NewTimer(s.cache.clientTimeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-ch:
+		if res.err == nil {
+			return res.resp, nil
+		}
+	case <-timer.C:
+	}
+
+	s.logCachedResult(id, true)
+	return stale, nil
+}
+
+// prefetch refreshes the cache entry for r ahead of its expiry. client
+// is the address of the client whose query triggered it, which decides
+// the client subnet sent upstream.
+func (s *server) prefetch(client net.IP, r *dns.Msg) {
+	id := s.newRequestID()
+	ctx, span := tracer.Start(withPrefetch(withClientIP(context.Background(), client)), "dns.prefetch",
+		trace.WithAttributes(questionAttributes(r)...),
+		trace.WithAttributes(attribute.String("dns.id", id)))
+	defer span.End()
+
+	scope := s.ecsScope(ctx, r)
+	s.inflight.do(coalesceKey(r, scope), id, func() (*dns.Msg, error) {
+		return s.resolveAndCache(ctx, id, r)
+	})
+	s.cache.prefetchDone(r, scope)
+}
+
+func (s *server) resolveAndCache(ctx context.Context, id string, r *dns.Msg) (*dns.Msg, error) {
+	var (
+		resp *dns.Msg
+		err  error
+	)
+	if s.validator != nil && !r.CheckingDisabled {
+		resp, err = s.resolveValidated(ctx, id, r)
+	} else {
+		resp, err = s.resolve(ctx, id, r)
+	}
+	if err == nil && s.rebind != nil {
+		var blocked []net.IP
+		resp, blocked = s.rebind.filter(r, resp, s.hasOverride)
+		if len(blocked) > 0 {
+			s.logRebind(id, r, blocked)
+			trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("dns.rebind_blocked", true))
+		}
+	}
+	if s.cache != nil {
+		scope := s.ecsScope(ctx, r)
+		if err == nil {
+			s.cache.put(r, resp, scope)
+		} else {
+			s.cache.markFailed(r, scope)
+		}
+	}
+	return resp, err
+}
+
+// resolveValidated resolves r with the DO bit set and checks the
+// answer's signatures. Bogus answers are replaced with SERVFAIL; secure
+// ones get the AD bit if the client asked for DNSSEC.
+func (s *server) resolveValidated(ctx context.Context, id string, r *dns.Msg) (*dns.Msg, error) {
+	resp, err := s.resolve(ctx, id, withDO(r))
+	if err != nil {
+		return nil, err
+	}
+	resp = resp.Copy()
+
+	result, verr := s.validator.validate(ctx, r, resp)
+	s.logValidation(id, r, result, verr)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("dns.dnssec", result.String()))
+
+	if result == validationBogus {
+		return bogusResponse(r, verr), nil
+	}
+
+	clientDO := false
+	if opt := r.IsEdns0(); opt != nil {
+		clientDO = opt.Do()
+	}
+	resp.AuthenticatedData = result == validationSecure && (clientDO || r.AuthenticatedData)
+	if !clientDO {
+		stripDNSSEC(r, resp)
+	}
+	return resp, nil
+}
+
+// dnssecLookup fetches the DNSKEY and DS records the validator needs
+// from the backends.
+func (s *server) dnssecLookup(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.SetEdns0(4096, true)
+	return s.resolve(ctx, s.newRequestID(), m)
+}
+
+type prefetchKey struct{}
+
+// withPrefetch marks upstream queries made with ctx as cache prefetches
+// so they can be told apart from client queries in the query log.
+func withPrefetch(ctx context.Context) context.Context {
+	return context.WithValue(ctx, prefetchKey{}, true)
+}
+
+func isPrefetch(ctx context.Context) bool {
+	v, _ := ctx.Value(prefetchKey{}).(bool)
+	return v
+}
+
+var errAllBackendsFailed = errors.New("all backends failed")
+
+func (s *server) resolve(ctx context.Context, id string, r *dns.Msg) (*dns.Msg, error) {
+	mode := conf.Config_ResolveMode(s.mode.Load())
+	switch mode {
 	case conf.Config_Random:
 		clients := s.shufClients()
-		s.handleRequestSerially(ctx, id, w, r, clients)
+		return s.resolveSerially(ctx, id, r, clients)
 	case conf.Config_InOrder:
-		s.handleRequestSerially(ctx, id, w, r, s.clients)
+		return s.resolveSerially(ctx, id, r, s.enabledClients())
 	case conf.Config_Concurrent:
-		s.handleRequestConcurrent(ctx, id, w, r)
+		return s.resolveConcurrent(ctx, id, r)
+	default:
+		return nil, fmt.Errorf("unknown resolve mode %s", mode)
 	}
 }
 
-func (s *server) handleRequestSerially(ctx context.Context, id string, w dns.ResponseWriter, r *dns.Msg, clients []*client) {
+func (s *server) resolveSerially(ctx context.Context, id string, r *dns.Msg, clients []*client) (*dns.Msg, error) {
 	for _, c := range clients {
 		result := s.queryBackend(ctx, c, id, r)
 		if result.err == nil {
-			w.WriteMsg(result.r)
 			s.logFirstResult(r, result)
-			return
+			return result.r, nil
 		} else {
 			s.logResult(r, result)
 		}
 	}
 
 	s.logFailure(r, id, len(clients))
+	return nil, errAllBackendsFailed
 }
 
-func (s *server) handleRequestConcurrent(ctx context.Context, id string, w dns.ResponseWriter, r *dns.Msg) {
-	ch := make(chan queryResult)
+func (s *server) resolveConcurrent(ctx context.Context, id string, r *dns.Msg) (*dns.Msg, error) {
 	clients := s.shufClients()
+	ch := make(chan queryResult, len(clients))
 	for _, c := range clients {
 		c := c
 		go func() {
@@ -200,15 +642,14 @@ func (s *server) handleRequestConcurrent(ctx context.Context, id string, w dns.R
 		}()
 	}
 
-	done := make(chan struct{})
+	first := make(chan *dns.Msg, 1)
 	go func() {
 		var sentResult bool
 		for range clients {
 			result := <-ch
 			if !sentResult && result.err == nil {
-				w.WriteMsg(result.r)
 				s.logFirstResult(r, result)
-				close(done)
+				first <- result.r
 				sentResult = true
 			}
 
@@ -217,15 +658,31 @@ func (s *server) handleRequestConcurrent(ctx context.Context, id string, w dns.R
 
 		if !sentResult {
 			s.logFailure(r, id, len(clients))
+			close(first)
 		}
 	}()
 
-	<-done
+	resp, ok := <-first
+	if !ok {
+		return nil, errAllBackendsFailed
+	}
+	return resp, nil
+}
+
+// enabledClients returns the backends that have not been disabled
+// through the admin api, in config order.
+func (s *server) enabledClients() []*client {
+	list := make([]*client, 0, len(s.clients))
+	for _, c := range s.clients {
+		if !c.disabled.Load() {
+			list = append(list, c)
+		}
+	}
+	return list
 }
 
 func (s *server) shufClients() []*client {
-	list := make([]*client, len(s.clients))
-	copy(list, s.clients)
+	list := s.enabledClients()
 	rand.Shuffle(len(list), func(i, j int) {
 		list[i], list[j] = list[j], list[i]
 	})
@@ -233,8 +690,46 @@ func (s *server) shufClients() []*client {
 }
 
 func (s *server) queryBackend(ctx context.Context, c *client, id string, m *dns.Msg) queryResult {
+	ctx, span := tracer.Start(ctx, "backend.exchange",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("dns.backend", c.name),
+			attribute.String("dns.transit", c.mode.String()),
+		))
+	defer span.End()
+
+	ecs := c.ecs
+	if ecs == nil {
+		ecs = stripECS
+	}
+	orig := m
+	m = ecs.query(m, clientIP(ctx))
+
+	var tcp bool
 	t0 := time.Now()
-	r, rtt, err := c.exchanger.Exchange(ctx, m)
+	r, rtt, addr, err := c.exchange(withUsedTCPFunc(ctx, func() { tcp = true }), m)
+	c.stats.record(time.Since(t0), err)
+	span.SetAttributes(attribute.String("dns.backend_addr", addr))
+	// The query is tapped once the address and transport it went over
+	// are known; its query time is still t0.
+	s.tap.forwarderQuery(c, addr, tcp, m, t0)
+	s.tap.forwarderResponse(c, addr, tcp, m, r, t0)
+	if err == nil {
+		ecs.response(orig, r)
+	}
+
+	var pinErr *doh.PinError
+	if errors.As(err, &pinErr) {
+		s.logPinMismatch(id, c, addr, m, pinErr)
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else if r != nil {
+		span.SetAttributes(attribute.String("dns.rcode", dns.RcodeToString[r.Rcode]))
+	}
+
 	return queryResult{
 		r:         r,
 		id:        id,
@@ -243,7 +738,8 @@ func (s *server) queryBackend(ctx context.Context, c *client, id string, m *dns.
 		queryTime: time.Since(t0),
 		name:      c.name,
 		mode:      c.mode,
-		addr:      c.addr,
+		addr:      addr,
+		prefetch:  isPrefetch(ctx),
 	}
 }
 
@@ -256,6 +752,61 @@ type queryResult struct {
 	name      string
 	mode      transitMode
 	addr      string
+	prefetch  bool
+}
+
+// logQuestion holds the structured fields describing the question of a
+// message. It is embedded in log events so the fields can be queried
+// without parsing req.
+type logQuestion struct {
+	QName  string `json:"qname,omitempty"`
+	QType  string `json:"qtype,omitempty"`
+	QClass string `json:"qclass,omitempty"`
+}
+
+func newLogQuestion(m *dns.Msg) logQuestion {
+	if len(m.Question) == 0 {
+		return logQuestion{}
+	}
+	q := m.Question[0]
+	return logQuestion{
+		QName:  q.Name,
+		QType:  dns.Type(q.Qtype).String(),
+		QClass: dns.Class(q.Qclass).String(),
+	}
+}
+
+// logAnswer holds the structured fields describing a response.
+type logAnswer struct {
+	Rcode    string `json:"rcode"`
+	Answers  int    `json:"answers"`
+	RespSize int    `json:"resp_size"`
+}
+
+func newLogAnswer(m *dns.Msg) *logAnswer {
+	if m == nil {
+		return nil
+	}
+	return &logAnswer{
+		Rcode:    dns.RcodeToString[m.Rcode],
+		Answers:  len(m.Answer),
+		RespSize: m.Len(),
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// clientTransport returns the transport a client query arrived over.
+func clientTransport(w dns.ResponseWriter) string {
+	if _, ok := w.RemoteAddr().(*net.TCPAddr); ok {
+		return "tcp"
+	}
+	return "udp"
 }
 
 type logResultMsg struct {
@@ -266,12 +817,15 @@ type logResultMsg struct {
 	Backend     string    `json:"backend"`
 	Mode        string    `json:"mode"`
 	BackendAddr string    `json:"backend_addr"`
-	Error       error     `json:"error,omitempty"`
+	Error       string    `json:"error,omitempty"`
 	Req         string    `json:"req"`
+	logQuestion
+	*logAnswer
+	Prefetch bool `json:"prefetch,omitempty"`
 }
 
 func (s *server) logResult(req *dns.Msg, result queryResult) {
-	if !s.logQueries {
+	if !s.logQueries.Load() {
 		return
 	}
 	rr := msg{*req}
@@ -283,19 +837,23 @@ func (s *server) logResult(req *dns.Msg, result queryResult) {
 		Backend:     result.name,
 		Mode:        result.mode.String(),
 		BackendAddr: result.addr,
-		Error:       result.err,
+		Error:       errString(result.err),
 		Req:         rr.String(),
+		logQuestion: newLogQuestion(req),
+		logAnswer:   newLogAnswer(result.r),
+		Prefetch:    result.prefetch,
 	}
 
 	s.logJSON(m)
 }
 
 type logFailureMsg struct {
-	TS           time.Time `json:"ts"`
-	Evt          string    `json:"evt"`
-	ID           string    `json:"id"`
-	Req          string    `json:"req"`
-	BackendCount int       `json:"backend_count"`
+	TS  time.Time `json:"ts"`
+	Evt string    `json:"evt"`
+	ID  string    `json:"id"`
+	Req string    `json:"req"`
+	logQuestion
+	BackendCount int `json:"backend_count"`
 }
 
 func (s *server) logFailure(req *dns.Msg, id string, backendCount int) {
@@ -305,6 +863,7 @@ func (s *server) logFailure(req *dns.Msg, id string, backendCount int) {
 		Evt:          "query_failure",
 		ID:           id,
 		Req:          rr.String(),
+		logQuestion:  newLogQuestion(req),
 		BackendCount: backendCount,
 	}
 
@@ -312,6 +871,9 @@ func (s *server) logFailure(req *dns.Msg, id string, backendCount int) {
 }
 
 func (s *server) logJSON(m interface{}) {
+	if s.logFields != nil {
+		m = s.logFields.filter(m)
+	}
 	s.logStream.Encode(m)
 }
 
@@ -324,10 +886,13 @@ type logFirstResultMsg struct {
 	Mode        string    `json:"mode"`
 	BackendAddr string    `json:"backend_addr"`
 	Result      string    `json:"result"`
+	logQuestion
+	*logAnswer
+	Prefetch bool `json:"prefetch,omitempty"`
 }
 
 func (s *server) logFirstResult(req *dns.Msg, result queryResult) {
-	if !s.logQueries {
+	if !s.logQueries.Load() {
 		return
 	}
 	rr := msg{*result.r}
@@ -341,28 +906,203 @@ func (s *server) logFirstResult(req *dns.Msg, result queryResult) {
 		Mode:        result.mode.String(),
 		BackendAddr: result.addr,
 		Result:      rr.String(),
+		logQuestion: newLogQuestion(req),
+		logAnswer:   newLogAnswer(result.r),
+		Prefetch:    result.prefetch,
 	}
 
 	s.logJSON(m)
 }
 
 type logRequest struct {
-	TS  time.Time `json:"ts"`
-	Evt string    `json:"evt"`
-	ID  string    `json:"id"`
-	Req string    `json:"req"`
+	TS         time.Time `json:"ts"`
+	Evt        string    `json:"evt"`
+	ID         string    `json:"id"`
+	ClientAddr string    `json:"client_addr"`
+	Transport  string    `json:"transport"`
+	Req        string    `json:"req"`
+	logQuestion
+	EDNS        bool   `json:"edns"`
+	EDNSUDPSize uint16 `json:"edns_udp_size,omitempty"`
+	EDNSDo      bool   `json:"edns_do,omitempty"`
+	CD          bool   `json:"cd,omitempty"`
 }
 
-func (s *server) logRequest(id string, req *dns.Msg) {
-	if !s.logQueries {
+func (s *server) logRequest(id string, w dns.ResponseWriter, req *dns.Msg) {
+	if !s.logQueries.Load() {
 		return
 	}
 	rr := msg{*req}
-	m := logResultMsg{
-		TS:  time.Now(),
-		Evt: "request",
-		ID:  id,
-		Req: rr.String(),
+	m := logRequest{
+		TS:          time.Now(),
+		Evt:         "request",
+		ID:          id,
+		ClientAddr:  w.RemoteAddr().String(),
+		Transport:   clientTransport(w),
+		Req:         rr.String(),
+		logQuestion: newLogQuestion(req),
+		CD:          req.CheckingDisabled,
+	}
+	if opt := req.IsEdns0(); opt != nil {
+		m.EDNS = true
+		m.EDNSUDPSize = opt.UDPSize()
+		m.EDNSDo = opt.Do()
+	}
+
+	s.logJSON(m)
+}
+
+type logResponseMsg struct {
+	TS         time.Time `json:"ts"`
+	Evt        string    `json:"evt"`
+	ID         string    `json:"id"`
+	DurationUS int64     `json:"duration_us"`
+	ClientAddr string    `json:"client_addr"`
+	Transport  string    `json:"transport"`
+	logQuestion
+	*logAnswer
+	Override bool `json:"override"`
+}
+
+// logResponse records the response sent to a client. override is set
+// when the answer came from the local overrides rather than the cache or
+// a backend.
+func (s *server) logResponse(id string, w dns.ResponseWriter, req, resp *dns.Msg, d time.Duration, override bool) {
+	if !s.logQueries.Load() {
+		return
+	}
+	m := logResponseMsg{
+		TS:          time.Now(),
+		Evt:         "response",
+		ID:          id,
+		DurationUS:  d.Microseconds(),
+		ClientAddr:  w.RemoteAddr().String(),
+		Transport:   clientTransport(w),
+		logQuestion: newLogQuestion(req),
+		logAnswer:   newLogAnswer(resp),
+		Override:    override,
+	}
+
+	s.logJSON(m)
+}
+
+type logValidationMsg struct {
+	TS     time.Time `json:"ts"`
+	Evt    string    `json:"evt"`
+	ID     string    `json:"id"`
+	Result string    `json:"dnssec"`
+	Error  string    `json:"error,omitempty"`
+	logQuestion
+}
+
+func (s *server) logValidation(id string, req *dns.Msg, result validationResult, err error) {
+	if !s.logQueries.Load() && result != validationBogus {
+		return
+	}
+	m := logValidationMsg{
+		TS:          time.Now(),
+		Evt:         "dnssec",
+		ID:          id,
+		Result:      result.String(),
+		Error:       errString(err),
+		logQuestion: newLogQuestion(req),
+	}
+
+	s.logJSON(m)
+}
+
+type logRebindMsg struct {
+	TS      time.Time `json:"ts"`
+	Evt     string    `json:"evt"`
+	ID      string    `json:"id"`
+	Blocked []string  `json:"blocked"`
+	logQuestion
+}
+
+// logRebind records an upstream answer that pointed a public name at
+// blocked addresses. These are always logged.
+func (s *server) logRebind(id string, req *dns.Msg, blocked []net.IP) {
+	m := logRebindMsg{
+		TS:          time.Now(),
+		Evt:         "rebind_blocked",
+		ID:          id,
+		logQuestion: newLogQuestion(req),
+	}
+	for _, ip := range blocked {
+		m.Blocked = append(m.Blocked, ip.String())
+	}
+
+	s.logJSON(m)
+}
+
+type logPinMismatchMsg struct {
+	TS         time.Time `json:"ts"`
+	Evt        string    `json:"evt"`
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Addr       string    `json:"addr"`
+	ServerName string    `json:"server_name"`
+	Keys       []string  `json:"keys"`
+	logQuestion
+}
+
+// logPinMismatch records a DoH server that presented none of its pinned
+// keys, which may mean the connection is being intercepted. These are
+// always logged.
+func (s *server) logPinMismatch(id string, c *client, addr string, req *dns.Msg, err *doh.PinError) {
+	m := logPinMismatchMsg{
+		TS:          time.Now(),
+		Evt:         "pin_mismatch",
+		ID:          id,
+		Name:        c.name,
+		Addr:        addr,
+		ServerName:  err.ServerName,
+		Keys:        err.Keys,
+		logQuestion: newLogQuestion(req),
+	}
+
+	s.logJSON(m)
+}
+
+type logCoalescedMsg struct {
+	TS         time.Time `json:"ts"`
+	Evt        string    `json:"evt"`
+	ID         string    `json:"id"`
+	LeaderID   string    `json:"leader_id"`
+	DurationUS int64     `json:"duration_us"`
+}
+
+func (s *server) logCoalesced(id, leaderID string, d time.Duration) {
+	if !s.logQueries.Load() {
+		return
+	}
+	m := logCoalescedMsg{
+		TS:         time.Now(),
+		Evt:        "coalesced",
+		ID:         id,
+		LeaderID:   leaderID,
+		DurationUS: d.Microseconds(),
+	}
+
+	s.logJSON(m)
+}
+
+type logCachedResultMsg struct {
+	TS    time.Time `json:"ts"`
+	Evt   string    `json:"evt"`
+	ID    string    `json:"id"`
+	Stale bool      `json:"stale,omitempty"`
+}
+
+func (s *server) logCachedResult(id string, stale bool) {
+	if !s.logQueries.Load() {
+		return
+	}
+	m := logCachedResultMsg{
+		TS:    time.Now(),
+		Evt:   "cached_result",
+		ID:    id,
+		Stale: stale,
 	}
 
 	s.logJSON(m)
@@ -416,33 +1156,114 @@ type client struct {
 	name      string
 	mode      transitMode
 	exchanger exchanger
-	addr      string
+	addr      string     // the configured host:port
+	ecs       *ecsPolicy // nil strips client subnets
+
+	disabled atomic.Bool
+	stats    backendStats
 }
 
 type exchanger interface {
 	Exchange(ctx context.Context, m *dns.Msg) (r *dns.Msg, rtt time.Duration, err error)
 }
 
-func newDOHClient(url string, addr string) *client {
-	dohClient, err := doh.New(url, addr)
+// exchange sends m to the backend and also returns the address it was
+// sent to. That is c.addr unless the DoH client picked one of several
+// addresses or looked the server up itself.
+func (c *client) exchange(ctx context.Context, m *dns.Msg) (r *dns.Msg, rtt time.Duration, addr string, err error) {
+	addr = c.addr
+	ctx = doh.WithRemoteAddrFunc(ctx, func(a string) {
+		addr = a
+	})
+	r, rtt, err = c.exchanger.Exchange(ctx, m)
+	return r, rtt, addr, err
+}
+
+// dohOptions returns the doh.Client options configured for s.
+func dohOptions(config *conf.Config, s conf.Server) ([]doh.Option, error) {
+	var opts []doh.Option
+	if len(s.HostPort) == 0 {
+		opts = append(opts, doh.WithBootstrap(config.BootstrapServers...))
+	}
+	if s.DisablePadding {
+		opts = append(opts, doh.WithPadding(0))
+	} else if s.PaddingBlockSize > 0 {
+		opts = append(opts, doh.WithPadding(int(s.PaddingBlockSize)))
+	}
+	if s.DohMethod == conf.Server_GET {
+		opts = append(opts, doh.WithGET())
+	}
+	if s.Http3 {
+		opts = append(opts, doh.WithHTTP3())
+	}
+	if len(s.SpkiPins) > 0 {
+		opts = append(opts, doh.WithSPKIPins(s.SpkiPins...))
+	}
+	if s.CaFile != "" {
+		pem, err := os.ReadFile(s.CaFile)
+		if err != nil {
+			return nil, fmt.Errorf("ca_file: %w", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file: no certificates found in %s", s.CaFile)
+		}
+		opts = append(opts, doh.WithRootCAs(roots))
+	}
+	if (s.ClientCertFile == "") != (s.ClientKeyFile == "") {
+		return nil, errors.New("client_cert_file and client_key_file must be set together")
+	}
+	if s.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.ClientCertFile, s.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		opts = append(opts, doh.WithClientCertificate(cert))
+	}
+	if s.Proxy != "" {
+		dial, err := newProxyDialer(s.Proxy, s.Name)
+		if err != nil {
+			return nil, fmt.Errorf("proxy: %w", err)
+		}
+		opts = append(opts, doh.WithDialer(dial))
+	}
+	return opts, nil
+}
+
+func newDOHClient(providerName, url string, addrs []string, opts ...doh.Option) (*client, error) {
+	var first string
+	if len(addrs) > 0 {
+		first = addrs[0]
+		opts = append([]doh.Option{doh.WithServerAddrs(addrs[1:]...)}, opts...)
+	}
+	dohClient, err := doh.New(url, first, opts...)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	// With several addresses, or none when the server is looked up with
+	// the bootstrap servers, each query reports the one it used.
+	addr := dohClient.Host()
+	if len(addrs) > 0 {
+		addr = addrs[0]
 	}
 	return &client{
-		name:      url,
+		name:      providerName,
 		addr:      addr,
 		mode:      dohTransitMode,
 		exchanger: dohClient,
-	}
+	}, nil
 }
 
-func newClassicClient(providerName string, addr string) *client {
+// newClassicClient creates a client for a plain DNS server. If dial is
+// set, queries are sent over TCP connections made with it.
+func newClassicClient(providerName string, addr string, dial dialFunc) *client {
 	return &client{
 		name: providerName,
 		mode: classicTransitMode,
 		addr: addr,
 		exchanger: &classicClient{
 			addr: addr,
+			dial: dial,
 			c:    &dns.Client{},
 		},
 	}
@@ -451,36 +1272,93 @@ func newClassicClient(providerName string, addr string) *client {
 type classicClient struct {
 	addr string
 	c    *dns.Client
+	dial dialFunc // set when queries go through a proxy
+}
+
+type usedTCPKey struct{}
+
+// withUsedTCPFunc returns a copy of ctx that makes classicClient.Exchange
+// call fn when the query goes over tcp rather than udp.
+func withUsedTCPFunc(ctx context.Context, fn func()) context.Context {
+	return context.WithValue(ctx, usedTCPKey{}, fn)
+}
+
+func usedTCP(ctx context.Context) {
+	if fn, ok := ctx.Value(usedTCPKey{}).(func()); ok {
+		fn()
+	}
 }
 
 func (c *classicClient) Exchange(ctx context.Context, m *dns.Msg) (r *dns.Msg, rtt time.Duration, err error) {
-	return c.c.ExchangeContext(ctx, m, c.addr)
+	if c.dial != nil {
+		usedTCP(ctx)
+		conn, err := c.dial(ctx, "tcp", c.addr)
+		if err != nil {
+			return nil, 0, err
+		}
+		defer conn.Close()
+		tcp := &dns.Client{Net: "tcp"}
+		return tcp.ExchangeWithConnContext(ctx, m, &dns.Conn{Conn: conn})
+	}
+
+	r, rtt, err = c.c.ExchangeContext(ctx, m, c.addr)
+	if err == nil && r.Truncated {
+		// Answers with DNSSEC records often don't fit in a udp packet;
+		// retry over tcp rather than handing back an empty answer.
+		usedTCP(ctx)
+		tcp := &dns.Client{Net: "tcp"}
+		return tcp.ExchangeContext(ctx, m, c.addr)
+	}
+	return r, rtt, err
 }
 
+// loadOverrides reads the override file at path. An entry whose ip
+// doesn't parse fails the whole file, so a bad edit can't replace the
+// entries already loaded.
 func loadOverrides(path string) (map[string]string, error) {
-	f, err := os.Open(path)
+	names := make(map[string]string)
+
+	var badIP error
+	err := readOverrides(path, func(lineNo int, ip string, hosts []string) {
+		if net.ParseIP(ip) == nil {
+			if badIP == nil {
+				badIP = fmt.Errorf("%s:%d: invalid ip %q", path, lineNo, ip)
+			}
+			return
+		}
+		for _, name := range hosts {
+			names[name] = ip
+		}
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	if badIP != nil {
+		return nil, badIP
+	}
 
-	names := make(map[string]string)
+	return names, nil
+}
 
-	r := bufio.NewReader(f)
-	for {
-		line, err := r.ReadString('\n')
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return nil, err
-		}
+// readOverrides parses the /etc/hosts style file at path and calls fn
+// with the line number, ip and fully qualified names of each entry. The
+// ip is left for fn to validate.
+func readOverrides(path string, fn func(lineNo int, ip string, names []string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
 
 		commentStart := strings.Index(line, "#")
 		if commentStart >= 0 {
 			line = line[:commentStart]
 		}
 
-		strings.TrimSpace(line)
 		fields := strings.Fields(line)
 
 		if len(fields) < 2 {
@@ -488,22 +1366,96 @@ func loadOverrides(path string) (map[string]string, error) {
 		}
 		ip := fields[0]
 
-		for _, name := range fields[1:] {
+		names := fields[1:]
+		for i, name := range names {
 			if !strings.HasSuffix(name, ".") {
-				name = name + "."
+				names[i] = name + "."
 			}
-			names[name] = ip
 		}
+
+		fn(lineNo, ip, names)
 	}
 
-	return names, nil
+	return scanner.Err()
+}
+
+// overrides holds the name to ip mappings loaded from the override file.
+// The whole map is swapped when the file is reloaded so lookups never
+// see a partially parsed file. Entries added at runtime through the
+// admin api are kept separately and take precedence over the file.
+type overrides struct {
+	mu      sync.RWMutex
+	names   map[string]string
+	dynamic map[string]string
+}
+
+// hasOverride reports whether name has a local override entry.
+func (s *server) hasOverride(name string) bool {
+	return s.localOverrides.lookup(name) != "" || s.localOverrides.lookup(strings.ToLower(name)) != ""
+}
+
+func (o *overrides) lookup(name string) string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if ip, ok := o.dynamic[name]; ok {
+		return ip
+	}
+	return o.names[name]
+}
+
+func (o *overrides) set(name, ip string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.dynamic == nil {
+		o.dynamic = make(map[string]string)
+	}
+	o.dynamic[name] = ip
+}
+
+// remove deletes a runtime override for name. It reports whether one
+// existed.
+func (o *overrides) remove(name string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	_, ok := o.dynamic[name]
+	delete(o.dynamic, name)
+	return ok
+}
+
+// inFile reports whether name has an entry loaded from the override
+// file.
+func (o *overrides) inFile(name string) bool {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	_, ok := o.names[name]
+	return ok
+}
+
+// all returns a copy of the effective overrides.
+func (o *overrides) all() map[string]string {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	m := make(map[string]string, len(o.names)+len(o.dynamic))
+	for k, v := range o.names {
+		m[k] = v
+	}
+	for k, v := range o.dynamic {
+		m[k] = v
+	}
+	return m
+}
+
+func (o *overrides) replace(names map[string]string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.names = names
 }
 
 func (s *server) localOverrideResponse(r *dns.Msg) *dns.Msg {
 	var localOverrideCount int
 	localOverrides := make([]net.IP, len(r.Question))
 	for i, q := range r.Question {
-		if ip := s.localOverrides[q.Name]; ip != "" {
+		if ip := s.localOverrides.lookup(q.Name); ip != "" {
 			parsedIP := net.ParseIP(ip)
 			if parsedIP == nil {
 				continue
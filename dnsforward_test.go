@@ -0,0 +1,540 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+	"github.com/psanford/dnsforward/dnstap"
+)
+
+func TestOverrideReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte("10.0.0.1 vm1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{localOverrides: &overrides{}, done: make(chan struct{})}
+	defer close(s.done)
+	if err := s.watchOverrides(path, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	req := new(dns.Msg)
+	req.SetQuestion("vm2.", dns.TypeA)
+	if resp := s.localOverrideResponse(req); resp != nil {
+		t.Fatalf("unexpected override for vm2 before reload: %s", resp)
+	}
+
+	// make sure the mtime moves even on filesystems with coarse timestamps
+	future := time.Now().Add(time.Minute)
+	if err := os.WriteFile(path, []byte("10.0.0.1 vm1\n10.0.0.2 vm2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, future, future)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if resp := s.localOverrideResponse(req); resp != nil {
+			a := resp.Answer[0].(*dns.A)
+			if a.A.String() != "10.0.0.2" {
+				t.Fatalf("got %s, expected 10.0.0.2", a.A)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("override file was not reloaded")
+}
+
+func TestOverrideReloadError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte("10.0.0.1 vm1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &server{localOverrides: &overrides{}, done: make(chan struct{})}
+	defer close(s.done)
+	if err := s.watchOverrides(path, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	future := time.Now().Add(time.Minute)
+	if err := os.WriteFile(path, []byte("10.0.0.2 vm2\n10.0.0.300 vm3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, future, future)
+
+	if _, err := loadOverrides(path); err == nil || !strings.Contains(err.Error(), ":2: invalid ip") {
+		t.Fatalf("expected an invalid ip error for line 2, got %v", err)
+	}
+
+	// Give the watcher several chances to pick up the broken file.
+	time.Sleep(100 * time.Millisecond)
+
+	if ip := s.localOverrides.lookup("vm1."); ip != "10.0.0.1" {
+		t.Errorf("vm1 = %q after a failed reload, expected the previous 10.0.0.1", ip)
+	}
+	if ip := s.localOverrides.lookup("vm2."); ip != "" {
+		t.Errorf("vm2 = %q from a file that failed to load", ip)
+	}
+}
+
+func TestCoalesceIdenticalQueries(t *testing.T) {
+	release := make(chan struct{})
+	var calls int32
+	backend := exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return answerA(m, "192.0.2.1"), time.Millisecond, nil
+	})
+	s := newTestServer(conf.Config_Concurrent, backend, backend)
+
+	const n = 5
+	writers := make([]*recorder, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		req := new(dns.Msg)
+		req.SetQuestion("example.com.", dns.TypeA)
+		req.Id = uint16(100 + i)
+		writers[i] = &recorder{}
+		wg.Add(1)
+		go func(w *recorder) {
+			defer wg.Done()
+			s.handleRequest(w, req)
+		}(writers[i])
+	}
+
+	// wait until the leader has queried both backends, then give the
+	// other requests time to join its call; any that started their own
+	// would show up as extra upstream exchanges
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline) && atomic.LoadInt32(&calls) < 2; {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("got %d upstream exchanges, expected 2 (one per backend)", got)
+	}
+	for i, w := range writers {
+		if w.msg == nil {
+			t.Fatalf("request %d got no response", i)
+		}
+		if w.msg.Id != uint16(100+i) {
+			t.Errorf("request %d got id %d", i, w.msg.Id)
+		}
+		if len(w.msg.Answer) != 1 {
+			t.Errorf("request %d got %d answers", i, len(w.msg.Answer))
+		}
+	}
+}
+
+type exchangeFunc func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error)
+
+func (f exchangeFunc) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+	return f(ctx, m)
+}
+
+func newTestServer(mode conf.Config_ResolveMode, backends ...exchanger) *server {
+	s := &server{
+		mux:            dns.NewServeMux(),
+		logStream:      json.NewEncoder(io.Discard),
+		localOverrides: &overrides{},
+	}
+	s.mode.Store(int32(mode))
+	for i, b := range backends {
+		s.clients = append(s.clients, &client{
+			name:      fmt.Sprintf("backend-%d", i),
+			mode:      classicTransitMode,
+			exchanger: b,
+		})
+	}
+	return s
+}
+
+func answerA(req *dns.Msg, ip string) *dns.Msg {
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+	resp.Answer = append(resp.Answer, &dns.A{
+		Hdr: dns.RR_Header{
+			Name:   req.Question[0].Name,
+			Rrtype: dns.TypeA,
+			Class:  dns.ClassINET,
+			Ttl:    300,
+		},
+		A: net.ParseIP(ip),
+	})
+	return resp
+}
+
+type recorder struct {
+	mu     sync.Mutex
+	msg    *dns.Msg
+	remote net.Addr
+}
+
+func (r *recorder) WriteMsg(m *dns.Msg) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msg = m
+	return nil
+}
+
+func (r *recorder) LocalAddr() net.Addr {
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 53}
+}
+func (r *recorder) RemoteAddr() net.Addr {
+	if r.remote != nil {
+		return r.remote
+	}
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 40000}
+}
+func (r *recorder) Write(b []byte) (int, error) { return len(b), nil }
+func (r *recorder) Close() error                { return nil }
+func (r *recorder) TsigStatus() error           { return nil }
+func (r *recorder) TsigTimersOnly(bool)         {}
+func (r *recorder) Hijack()                     {}
+
+func TestServeStale(t *testing.T) {
+	var fail int32
+	backend := exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		if atomic.LoadInt32(&fail) == 1 {
+			return nil, 0, errors.New("network unreachable")
+		}
+		return answerA(m, "192.0.2.1"), time.Millisecond, nil
+	})
+	s := newTestServer(conf.Config_InOrder, backend)
+	s.cache = newCache(&conf.Cache{ServeStale: true})
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+	req.SetEdns0(1232, false)
+
+	w := &recorder{}
+	s.handleRequest(w, req)
+	if w.msg == nil || len(w.msg.Answer) != 1 {
+		t.Fatalf("unexpected first response: %v", w.msg)
+	}
+
+	// expire the entry and take the backend down
+	s.cache.mu.Lock()
+	for _, e := range s.cache.entries {
+		e.stored = e.stored.Add(-e.ttl - time.Second)
+	}
+	s.cache.mu.Unlock()
+	atomic.StoreInt32(&fail, 1)
+
+	w = &recorder{}
+	s.handleRequest(w, req)
+	if w.msg == nil || len(w.msg.Answer) != 1 {
+		t.Fatalf("expected stale answer, got %v", w.msg)
+	}
+	if ttl := w.msg.Answer[0].Header().Ttl; ttl != 30 {
+		t.Errorf("stale answer ttl %d, expected 30", ttl)
+	}
+	var ede *dns.EDNS0_EDE
+	if opt := w.msg.IsEdns0(); opt != nil {
+		for _, o := range opt.Option {
+			if e, ok := o.(*dns.EDNS0_EDE); ok {
+				ede = e
+			}
+		}
+	}
+	if ede == nil || ede.InfoCode != dns.ExtendedErrorCodeStaleAnswer {
+		t.Errorf("expected Stale Answer EDE, got %v", ede)
+	}
+
+	// backend comes back: the next query refreshes the entry
+	atomic.StoreInt32(&fail, 0)
+	s.cache.mu.Lock()
+	for _, e := range s.cache.entries {
+		e.refreshFailed = time.Time{}
+	}
+	s.cache.mu.Unlock()
+
+	w = &recorder{}
+	s.handleRequest(w, req)
+	if ttl := w.msg.Answer[0].Header().Ttl; ttl != 300 {
+		t.Errorf("refreshed answer ttl %d, expected 300", ttl)
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	prefetched := make(chan struct{}, 1)
+	var failPrefetch atomic.Bool
+	backend := exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		if isPrefetch(ctx) {
+			prefetched <- struct{}{}
+			if failPrefetch.Load() {
+				return nil, 0, errors.New("backend down")
+			}
+		}
+		return answerA(m, "192.0.2.1"), time.Millisecond, nil
+	})
+	s := newTestServer(conf.Config_InOrder, backend)
+	s.cache = newCache(&conf.Cache{PrefetchMinHits: 2})
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+	s.handleRequest(&recorder{}, req)
+
+	// one hit while plenty of ttl remains
+	s.handleRequest(&recorder{}, req)
+	select {
+	case <-prefetched:
+		t.Fatal("prefetched an entry far from expiry")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// age the entry to 5% of its ttl remaining
+	s.cache.mu.Lock()
+	for _, e := range s.cache.entries {
+		e.stored = e.stored.Add(-e.ttl * 95 / 100)
+	}
+	s.cache.mu.Unlock()
+
+	failPrefetch.Store(true)
+	s.handleRequest(&recorder{}, req)
+	select {
+	case <-prefetched:
+	case <-time.After(5 * time.Second):
+		t.Fatal("popular entry near expiry was not prefetched")
+	}
+
+	// A failed prefetch is tried again on a later hit.
+	failPrefetch.Store(false)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.handleRequest(&recorder{}, req)
+		select {
+		case <-prefetched:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("entry was not prefetched again after a failed prefetch")
+		}
+	}
+}
+
+func TestCachePersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache")
+
+	c := newCache(&conf.Cache{})
+	fresh := new(dns.Msg)
+	fresh.SetQuestion("fresh.example.com.", dns.TypeA)
+	c.put(fresh, answerA(fresh, "192.0.2.1"), "")
+
+	expired := new(dns.Msg)
+	expired.SetQuestion("expired.example.com.", dns.TypeA)
+	c.put(expired, answerA(expired, "192.0.2.2"), "")
+	c.entries[cacheKey(expired, "")].stored = time.Now().Add(-time.Hour)
+
+	if err := c.save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := newCache(&conf.Cache{})
+	n, err := loaded.load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("loaded %d entries, expected 1", n)
+	}
+
+	resp, ok, _ := loaded.get(fresh, "")
+	if !ok {
+		t.Fatal("fresh entry missing after load")
+	}
+	if a := resp.Answer[0].(*dns.A); a.A.String() != "192.0.2.1" {
+		t.Errorf("got %s, expected 192.0.2.1", a.A)
+	}
+	if resp, _, _ := loaded.get(expired, ""); resp != nil {
+		t.Errorf("expired entry was loaded: %s", resp)
+	}
+}
+
+func TestDnstap(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dnstap.fstrm")
+
+	s := newTestServer(conf.Config_InOrder, exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		return answerA(m, "192.0.2.1"), 0, nil
+	}))
+	s.clients[0].addr = "192.0.2.53:53"
+
+	var err error
+	s.tap, err = newTap(&conf.Dnstap{FilePath: path, Identity: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+	s.handleRequest(&recorder{}, req)
+	s.tap.close()
+
+	var types []dnstap.Message_Type
+	for _, d := range readDnstap(t, path) {
+		if string(d.Identity) != "test" {
+			t.Errorf("identity %q, expected test", d.Identity)
+		}
+		m := d.Message
+		types = append(types, m.GetType())
+
+		switch m.GetType() {
+		case dnstap.Message_FORWARDER_QUERY, dnstap.Message_FORWARDER_RESPONSE:
+			if !net.IP(m.ResponseAddress).Equal(net.IPv4(192, 0, 2, 53)) || m.GetResponsePort() != 53 {
+				t.Errorf("%s: response address %s:%d", m.GetType(), net.IP(m.ResponseAddress), m.GetResponsePort())
+			}
+			if m.GetSocketProtocol() != dnstap.SocketProtocol_UDP {
+				t.Errorf("%s: socket protocol %s, expected UDP", m.GetType(), m.GetSocketProtocol())
+			}
+		default:
+			if m.GetQueryPort() != 40000 {
+				t.Errorf("%s: query port %d, expected 40000", m.GetType(), m.GetQueryPort())
+			}
+		}
+
+		var q dns.Msg
+		if err := q.Unpack(m.QueryMessage); err != nil || q.Question[0].Name != "example.com." {
+			t.Errorf("%s: bad query message: %v", m.GetType(), err)
+		}
+	}
+
+	expect := []dnstap.Message_Type{
+		dnstap.Message_CLIENT_QUERY,
+		dnstap.Message_FORWARDER_QUERY,
+		dnstap.Message_FORWARDER_RESPONSE,
+		dnstap.Message_CLIENT_RESPONSE,
+	}
+	if fmt.Sprint(types) != fmt.Sprint(expect) {
+		t.Errorf("got messages %v, expected %v", types, expect)
+	}
+
+	// Opening the file again adds a stream after the first one.
+	first, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tap, err := newTap(&conf.Dnstap{FilePath: path, Identity: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tap.close()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) <= len(first) || !bytes.Equal(b[:len(first)], first) {
+		t.Errorf("reopening the dnstap file did not append to it")
+	}
+}
+
+func TestDnstapTCPFallback(t *testing.T) {
+	// A server whose udp answers are always truncated, so the query is
+	// retried over tcp.
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		resp := answerA(r, "192.0.2.1")
+		if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+			resp.Answer = nil
+			resp.Truncated = true
+		}
+		w.WriteMsg(resp)
+	})
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		t.Skipf("tcp port matching udp port unavailable: %s", err)
+	}
+	udpServer := &dns.Server{PacketConn: pc, Handler: handler}
+	tcpServer := &dns.Server{Listener: l, Handler: handler}
+	go udpServer.ActivateAndServe()
+	go tcpServer.ActivateAndServe()
+	defer udpServer.Shutdown()
+	defer tcpServer.Shutdown()
+
+	path := filepath.Join(t.TempDir(), "dnstap.fstrm")
+	s := newTestServer(conf.Config_InOrder)
+	s.clients = []*client{newClassicClient("classic", pc.LocalAddr().String(), nil)}
+	s.tap, err = newTap(&conf.Dnstap{FilePath: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+	w := &recorder{}
+	s.handleRequest(w, req)
+	s.tap.close()
+	if w.msg == nil || len(w.msg.Answer) != 1 {
+		t.Fatalf("expected the answer from the tcp retry, got %v", w.msg)
+	}
+
+	var forwarder int
+	for _, d := range readDnstap(t, path) {
+		m := d.Message
+		switch m.GetType() {
+		case dnstap.Message_FORWARDER_QUERY, dnstap.Message_FORWARDER_RESPONSE:
+			forwarder++
+			if m.GetSocketProtocol() != dnstap.SocketProtocol_TCP {
+				t.Errorf("%s: socket protocol %s, expected TCP", m.GetType(), m.GetSocketProtocol())
+			}
+		}
+	}
+	if forwarder != 2 {
+		t.Errorf("got %d forwarder messages, expected 2", forwarder)
+	}
+}
+
+// readDnstap returns the messages in the first frame stream of the
+// dnstap file at path.
+func readDnstap(t *testing.T, path string) []*dnstap.Dnstap {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Skip the START control frame, then read data frames until the
+	// STOP control frame's escape.
+	if len(b) < 8 || binary.BigEndian.Uint32(b) != 0 {
+		t.Fatalf("missing START frame: %x", b)
+	}
+	b = b[8+binary.BigEndian.Uint32(b[4:]):]
+
+	var msgs []*dnstap.Dnstap
+	for len(b) >= 4 {
+		n := binary.BigEndian.Uint32(b)
+		if n == 0 {
+			break
+		}
+		d := new(dnstap.Dnstap)
+		if err := d.Unmarshal(b[4 : 4+n]); err != nil {
+			t.Fatal(err)
+		}
+		b = b[4+n:]
+		msgs = append(msgs, d)
+	}
+	return msgs
+}
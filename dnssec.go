@@ -0,0 +1,836 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+)
+
+// defaultTrustAnchors are the DS records of the root zone KSKs.
+var defaultTrustAnchors = []string{
+	". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+	". IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+const (
+	minKeyCacheTTL = time.Minute
+	maxKeyCacheTTL = time.Hour
+	// maxKeyCacheZones bounds the number of zones whose keys are kept.
+	maxKeyCacheZones = 4096
+)
+
+type validationResult int
+
+const (
+	validationInsecure validationResult = iota
+	validationSecure
+	validationBogus
+)
+
+func (v validationResult) String() string {
+	switch v {
+	case validationInsecure:
+		return "insecure"
+	case validationSecure:
+		return "secure"
+	case validationBogus:
+		return "bogus"
+	default:
+		return fmt.Sprintf("unknown validation result<%d>", int(v))
+	}
+}
+
+// lookupFunc queries the backends for name with the DO bit set, without
+// validating the answer.
+type lookupFunc func(ctx context.Context, name string, qtype uint16) (*dns.Msg, error)
+
+// validator checks DNSSEC signatures in upstream responses. Keys are
+// authenticated along the DS chain from the trust anchors down to the
+// zone that signed an answer, fetching DNSKEY and DS records through the
+// backends.
+type validator struct {
+	anchors []*dns.DS
+	lookup  lookupFunc
+	now     func() time.Time
+
+	mu    sync.Mutex
+	zones map[string]*zoneKeys
+}
+
+// zoneKeys are the authenticated keys of a zone. keys is empty for a
+// zone that is provably unsigned.
+type zoneKeys struct {
+	keys    []*dns.DNSKEY
+	expires time.Time
+}
+
+func newValidator(c *conf.Dnssec, lookup lookupFunc) (*validator, error) {
+	anchors := c.TrustAnchors
+	if len(anchors) == 0 {
+		anchors = defaultTrustAnchors
+	}
+
+	v := &validator{
+		lookup: lookup,
+		now:    time.Now,
+		zones:  make(map[string]*zoneKeys),
+	}
+	for _, a := range anchors {
+		rr, err := dns.NewRR(a)
+		if err != nil {
+			return nil, fmt.Errorf("trust anchor %q: %w", a, err)
+		}
+		ds, ok := rr.(*dns.DS)
+		if !ok {
+			return nil, fmt.Errorf("trust anchor %q is not a DS record", a)
+		}
+		v.anchors = append(v.anchors, ds)
+	}
+	return v, nil
+}
+
+// validate checks resp, the answer to q. An error describes why a bogus
+// answer failed validation.
+func (v *validator) validate(ctx context.Context, q, resp *dns.Msg) (validationResult, error) {
+	if resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError {
+		return validationInsecure, nil
+	}
+
+	// The authority section goes first: its NSEC and NSEC3 records are
+	// what prove a wildcard answer.
+	secure := true
+	var denial []dns.RR
+	for _, set := range rrsets(resp.Ns) {
+		result, err := v.verifyRRset(ctx, set, resp.Ns, nil)
+		if err != nil {
+			return validationBogus, err
+		}
+		if result != validationSecure {
+			secure = false
+			continue
+		}
+		if t := set[0].Header().Rrtype; t == dns.TypeNSEC || t == dns.TypeNSEC3 {
+			denial = append(denial, set...)
+		}
+	}
+	for _, set := range rrsets(resp.Answer) {
+		result, err := v.verifyRRset(ctx, set, resp.Answer, denial)
+		if err != nil {
+			return validationBogus, err
+		}
+		if result != validationSecure {
+			secure = false
+		}
+	}
+
+	if len(resp.Answer) == 0 && len(q.Question) == 1 {
+		qname := q.Question[0].Name
+		if len(resp.Ns) == 0 {
+			insecure, err := v.provenInsecure(ctx, qname)
+			if err != nil {
+				return validationBogus, err
+			}
+			if !insecure {
+				return validationBogus, fmt.Errorf("unsigned negative answer for %s", qname)
+			}
+			return validationInsecure, nil
+		}
+		if secure {
+			insecure, err := checkDenial(q.Question[0], resp.Rcode, denial)
+			if err != nil {
+				return validationBogus, err
+			}
+			if insecure {
+				secure = false
+			}
+		}
+	}
+
+	if secure {
+		return validationSecure, nil
+	}
+	return validationInsecure, nil
+}
+
+// verifyRRset checks the signatures over set, which came from section.
+// An unsigned set is insecure only if its zone is provably unsigned. A
+// set expanded from a wildcard is secure only if the authenticated NSEC
+// or NSEC3 records in denial show no closer name exists (RFC 4035
+// 5.3.4, RFC 5155 8.8).
+func (v *validator) verifyRRset(ctx context.Context, set, section, denial []dns.RR) (validationResult, error) {
+	hdr := set[0].Header()
+	sigs := signaturesFor(set, section)
+	if len(sigs) == 0 {
+		insecure, err := v.provenInsecure(ctx, hdr.Name)
+		if err != nil {
+			return validationBogus, err
+		}
+		if !insecure {
+			return validationBogus, fmt.Errorf("missing signature for %s %s", hdr.Name, dns.Type(hdr.Rrtype))
+		}
+		return validationInsecure, nil
+	}
+
+	var lastErr error
+	for _, sig := range sigs {
+		if !dns.IsSubDomain(sig.SignerName, hdr.Name) {
+			lastErr = fmt.Errorf("%s signed by unrelated zone %s", hdr.Name, sig.SignerName)
+			continue
+		}
+		if hdr.Rrtype == dns.TypeDS && strings.EqualFold(sig.SignerName, hdr.Name) {
+			lastErr = fmt.Errorf("DS %s signed by its own zone", hdr.Name)
+			continue
+		}
+		if !sig.ValidityPeriod(v.now()) {
+			lastErr = fmt.Errorf("signature for %s %s is not currently valid", hdr.Name, dns.Type(hdr.Rrtype))
+			continue
+		}
+		if int(sig.Labels) > dns.CountLabel(hdr.Name) {
+			lastErr = fmt.Errorf("signature for %s %s has too many labels", hdr.Name, dns.Type(hdr.Rrtype))
+			continue
+		}
+
+		zk, err := v.keys(ctx, sig.SignerName)
+		if err != nil {
+			return validationBogus, err
+		}
+		if len(zk.keys) == 0 {
+			return validationInsecure, nil
+		}
+		for _, k := range zk.keys {
+			if k.KeyTag() != sig.KeyTag {
+				continue
+			}
+			err := sig.Verify(k, set)
+			if err == nil {
+				if wildcardExpanded(hdr.Name, sig) && !noCloserMatch(hdr.Name, int(sig.Labels), denial) {
+					return validationBogus, fmt.Errorf("no proof that %s %s has no closer match than its wildcard", hdr.Name, dns.Type(hdr.Rrtype))
+				}
+				return validationSecure, nil
+			}
+			lastErr = fmt.Errorf("%s %s: %w", hdr.Name, dns.Type(hdr.Rrtype), err)
+		}
+		if lastErr == nil {
+			lastErr = fmt.Errorf("no key %d in %s for %s %s", sig.KeyTag, sig.SignerName, hdr.Name, dns.Type(hdr.Rrtype))
+		}
+	}
+	return validationBogus, lastErr
+}
+
+// wildcardExpanded reports whether sig, a signature over records owned
+// by name, shows they were synthesized from a wildcard: its labels field
+// counts fewer labels than name has, not counting the wildcard's own.
+func wildcardExpanded(name string, sig *dns.RRSIG) bool {
+	labels := dns.CountLabel(name)
+	if strings.HasPrefix(name, "*.") {
+		labels--
+	}
+	return int(sig.Labels) < labels
+}
+
+// noCloserMatch reports whether denial proves that the next closer name
+// of name, the one with labels+1 labels, doesn't exist, so a wildcard
+// with labels labels below it was the best match for name.
+func noCloserMatch(name string, labels int, denial []dns.RR) bool {
+	nextCloser := ancestor(name, labels+1)
+	nsecs, nsec3s := splitDenial(denial)
+	for _, n := range nsecs {
+		if nsecCovers(n, nextCloser) {
+			return true
+		}
+	}
+	return nsec3Covering(nextCloser, nsec3s) != nil
+}
+
+// keys returns the authenticated keys of zone.
+func (v *validator) keys(ctx context.Context, zone string) (*zoneKeys, error) {
+	zone = dns.CanonicalName(zone)
+
+	v.mu.Lock()
+	zk := v.zones[zone]
+	v.mu.Unlock()
+	if zk != nil && v.now().Before(zk.expires) {
+		return zk, nil
+	}
+
+	ttl := maxKeyCacheTTL
+	minTTL := func(rrs []dns.RR) {
+		for _, rr := range rrs {
+			if d := time.Duration(rr.Header().Ttl) * time.Second; d < ttl {
+				ttl = d
+			}
+		}
+	}
+
+	var dsSet []*dns.DS
+	if zone == "." {
+		dsSet = v.anchors
+	} else {
+		resp, err := v.lookup(ctx, zone, dns.TypeDS)
+		if err != nil {
+			return nil, fmt.Errorf("DS %s: %w", zone, err)
+		}
+		set := rrsetOf(resp.Answer, zone, dns.TypeDS)
+		if len(set) == 0 {
+			insecure, err := v.provenInsecure(ctx, zone)
+			if err != nil {
+				return nil, err
+			}
+			if !insecure {
+				return nil, fmt.Errorf("no DS for signed zone %s", zone)
+			}
+			return v.storeKeys(zone, nil, minKeyCacheTTL), nil
+		}
+		result, err := v.verifyRRset(ctx, set, resp.Answer, nil)
+		if err != nil {
+			return nil, err
+		}
+		if result != validationSecure {
+			return v.storeKeys(zone, nil, minKeyCacheTTL), nil
+		}
+		minTTL(set)
+		for _, rr := range set {
+			dsSet = append(dsSet, rr.(*dns.DS))
+		}
+	}
+
+	resp, err := v.lookup(ctx, zone, dns.TypeDNSKEY)
+	if err != nil {
+		return nil, fmt.Errorf("DNSKEY %s: %w", zone, err)
+	}
+	set := rrsetOf(resp.Answer, zone, dns.TypeDNSKEY)
+	sigs := signaturesFor(set, resp.Answer)
+	minTTL(set)
+
+	if !anyDigestSupported(dsSet) {
+		// RFC 4035 5.2: a zone whose DS records all use unsupported
+		// algorithms is treated as unsigned.
+		return v.storeKeys(zone, nil, ttl), nil
+	}
+
+	for _, ds := range dsSet {
+		for _, rr := range set {
+			k := rr.(*dns.DNSKEY)
+			if k.KeyTag() != ds.KeyTag || k.Algorithm != ds.Algorithm {
+				continue
+			}
+			kds := k.ToDS(ds.DigestType)
+			if kds == nil {
+				continue
+			}
+			if !strings.EqualFold(kds.Digest, ds.Digest) {
+				continue
+			}
+			for _, sig := range sigs {
+				if sig.KeyTag == k.KeyTag() && sig.ValidityPeriod(v.now()) && sig.Verify(k, set) == nil {
+					var keys []*dns.DNSKEY
+					for _, rr := range set {
+						keys = append(keys, rr.(*dns.DNSKEY))
+					}
+					return v.storeKeys(zone, keys, ttl), nil
+				}
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no DNSKEY for %s matches its DS records", zone)
+}
+
+// anyDigestSupported reports whether any record in dsSet uses a digest
+// and key algorithm that can be validated.
+func anyDigestSupported(dsSet []*dns.DS) bool {
+	for _, ds := range dsSet {
+		switch ds.DigestType {
+		case dns.SHA1, dns.SHA256, dns.SHA384:
+		default:
+			continue
+		}
+		switch ds.Algorithm {
+		case dns.RSASHA1, dns.RSASHA1NSEC3SHA1, dns.RSASHA256, dns.RSASHA512,
+			dns.ECDSAP256SHA256, dns.ECDSAP384SHA384, dns.ED25519:
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) storeKeys(zone string, keys []*dns.DNSKEY, ttl time.Duration) *zoneKeys {
+	if ttl < minKeyCacheTTL {
+		ttl = minKeyCacheTTL
+	}
+	zk := &zoneKeys{keys: keys, expires: v.now().Add(ttl)}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := v.zones[zone]; !ok && len(v.zones) >= maxKeyCacheZones {
+		now := v.now()
+		for z, old := range v.zones {
+			if now.After(old.expires) {
+				delete(v.zones, z)
+			}
+		}
+		// Still full: drop an arbitrary zone, its keys are just fetched
+		// again.
+		for z := range v.zones {
+			if len(v.zones) < maxKeyCacheZones {
+				break
+			}
+			delete(v.zones, z)
+		}
+	}
+	v.zones[zone] = zk
+	return zk
+}
+
+// provenInsecure reports whether name is below an unsigned delegation.
+// It walks down from the root asking for the DS record at each label and
+// stops at the first delegation whose missing DS is proven by signed
+// NSEC or NSEC3 records.
+func (v *validator) provenInsecure(ctx context.Context, name string) (bool, error) {
+	name = dns.CanonicalName(name)
+	labels := dns.SplitDomainName(name)
+
+	for i := len(labels) - 1; i >= 0; i-- {
+		child := dns.Fqdn(strings.Join(labels[i:], "."))
+
+		v.mu.Lock()
+		zk := v.zones[child]
+		v.mu.Unlock()
+		if zk != nil && v.now().Before(zk.expires) {
+			if len(zk.keys) == 0 {
+				return true, nil
+			}
+			continue
+		}
+
+		resp, err := v.lookup(ctx, child, dns.TypeDS)
+		if err != nil {
+			return false, fmt.Errorf("DS %s: %w", child, err)
+		}
+
+		if set := rrsetOf(resp.Answer, child, dns.TypeDS); len(set) > 0 {
+			zk, err := v.keys(ctx, child)
+			if err != nil {
+				return false, err
+			}
+			if len(zk.keys) == 0 {
+				return true, nil
+			}
+			continue
+		}
+
+		if resp.Rcode == dns.RcodeNameError {
+			return false, nil
+		}
+
+		var denial []dns.RR
+		for _, set := range rrsets(resp.Ns) {
+			t := set[0].Header().Rrtype
+			if t != dns.TypeNSEC && t != dns.TypeNSEC3 {
+				continue
+			}
+			for _, sig := range signaturesFor(set, resp.Ns) {
+				if !dns.IsSubDomain(sig.SignerName, child) || strings.EqualFold(sig.SignerName, child) {
+					return false, fmt.Errorf("proof that %s has no DS is not signed by its parent", child)
+				}
+			}
+			result, err := v.verifyRRset(ctx, set, resp.Ns, nil)
+			if err != nil {
+				return false, err
+			}
+			if result != validationSecure {
+				return true, nil
+			}
+			denial = append(denial, set...)
+		}
+		if len(denial) == 0 {
+			return false, fmt.Errorf("no signed proof that %s has no DS", child)
+		}
+		if insecureDelegation(child, denial) {
+			v.storeKeys(child, nil, minKeyCacheTTL)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// insecureDelegation reports whether the NSEC or NSEC3 records in denial
+// show name to be a delegation without a DS record. For NSEC3 that is
+// either a matching record or an opt-out span covering the next closer
+// name of a closest encloser proof (RFC 5155 8.6).
+func insecureDelegation(name string, denial []dns.RR) bool {
+	nsecs, nsec3s := splitDenial(denial)
+	for _, n := range nsecs {
+		if equalNames(n.Hdr.Name, name) {
+			return isInsecureDelegation(n.TypeBitMap)
+		}
+	}
+	if n := nsec3Matching(name, nsec3s); n != nil {
+		return isInsecureDelegation(n.TypeBitMap)
+	}
+	_, cover, ok := nsec3ClosestEncloser(name, nsec3s)
+	return ok && cover.Flags&nsec3OptOut != 0
+}
+
+func isInsecureDelegation(bitmap []uint16) bool {
+	return hasType(bitmap, dns.TypeNS) && !hasType(bitmap, dns.TypeDS) && !hasType(bitmap, dns.TypeSOA)
+}
+
+const nsec3OptOut = 1
+
+// checkDenial checks that the authenticated NSEC or NSEC3 records in
+// denial prove the negative answer to q (RFC 4035 5.4, RFC 5155 8).
+// insecure is set when the proof relies on an NSEC3 opt-out span, which
+// can hide unsigned names.
+func checkDenial(q dns.Question, rcode int, denial []dns.RR) (insecure bool, err error) {
+	nsecs, nsec3s := splitDenial(denial)
+	switch {
+	case len(nsecs) > 0:
+		return false, checkNSECDenial(q, rcode, nsecs)
+	case len(nsec3s) > 0:
+		return checkNSEC3Denial(q, rcode, nsec3s)
+	}
+	return false, fmt.Errorf("missing proof of non-existence for %s %s", q.Name, dns.Type(q.Qtype))
+}
+
+func checkNSECDenial(q dns.Question, rcode int, nsecs []*dns.NSEC) error {
+	if rcode == dns.RcodeSuccess {
+		for _, n := range nsecs {
+			if equalNames(n.Hdr.Name, q.Name) {
+				return checkNoData(q, n.TypeBitMap)
+			}
+		}
+		for _, n := range nsecs {
+			// An empty non-terminal has no NSEC of its own; the one before
+			// it points at a name below it.
+			if nsecCovers(n, q.Name) && !equalNames(n.NextDomain, q.Name) && dns.IsSubDomain(q.Name, n.NextDomain) {
+				return nil
+			}
+		}
+		if ce, ok := nsecClosestEncloser(q.Name, nsecs); ok {
+			wildcard := wildcardName(ce)
+			for _, n := range nsecs {
+				if equalNames(n.Hdr.Name, wildcard) {
+					return checkNoData(q, n.TypeBitMap)
+				}
+			}
+		}
+		return fmt.Errorf("no NSEC proves %s has no %s records", q.Name, dns.Type(q.Qtype))
+	}
+
+	ce, ok := nsecClosestEncloser(q.Name, nsecs)
+	if !ok {
+		return fmt.Errorf("no NSEC proves %s doesn't exist", q.Name)
+	}
+	wildcard := wildcardName(ce)
+	for _, n := range nsecs {
+		if nsecCovers(n, wildcard) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no NSEC proves wildcard %s doesn't exist", wildcard)
+}
+
+func checkNSEC3Denial(q dns.Question, rcode int, nsec3s []*dns.NSEC3) (bool, error) {
+	if rcode == dns.RcodeSuccess {
+		if n := nsec3Matching(q.Name, nsec3s); n != nil {
+			return false, checkNoData(q, n.TypeBitMap)
+		}
+		ce, cover, ok := nsec3ClosestEncloser(q.Name, nsec3s)
+		if !ok {
+			return false, fmt.Errorf("no NSEC3 proves %s has no %s records", q.Name, dns.Type(q.Qtype))
+		}
+		if q.Qtype == dns.TypeDS && cover.Flags&nsec3OptOut != 0 {
+			return true, nil
+		}
+		if n := nsec3Matching(wildcardName(ce), nsec3s); n != nil {
+			return false, checkNoData(q, n.TypeBitMap)
+		}
+		return false, fmt.Errorf("no NSEC3 proves %s has no %s records", q.Name, dns.Type(q.Qtype))
+	}
+
+	if nsec3Matching(q.Name, nsec3s) != nil {
+		return false, fmt.Errorf("NSEC3 shows %s exists", q.Name)
+	}
+	ce, cover, ok := nsec3ClosestEncloser(q.Name, nsec3s)
+	if !ok {
+		return false, fmt.Errorf("no NSEC3 closest encloser proof for %s", q.Name)
+	}
+	wildcard := wildcardName(ce)
+	if nsec3Covering(wildcard, nsec3s) == nil {
+		return false, fmt.Errorf("no NSEC3 proves wildcard %s doesn't exist", wildcard)
+	}
+	return cover.Flags&nsec3OptOut != 0, nil
+}
+
+// checkNoData checks the type bitmap of the record proving the name
+// exists, for a NODATA answer to q.
+func checkNoData(q dns.Question, bitmap []uint16) error {
+	if hasType(bitmap, q.Qtype) || hasType(bitmap, dns.TypeCNAME) {
+		return fmt.Errorf("proof of non-existence for %s lists %s", q.Name, dns.Type(q.Qtype))
+	}
+	return nil
+}
+
+func splitDenial(denial []dns.RR) ([]*dns.NSEC, []*dns.NSEC3) {
+	var (
+		nsecs  []*dns.NSEC
+		nsec3s []*dns.NSEC3
+	)
+	for _, rr := range denial {
+		switch rr := rr.(type) {
+		case *dns.NSEC:
+			nsecs = append(nsecs, rr)
+		case *dns.NSEC3:
+			// Names can't be hashed with other algorithms.
+			if rr.Hash == dns.SHA1 {
+				nsec3s = append(nsec3s, rr)
+			}
+		}
+	}
+	return nsecs, nsec3s
+}
+
+// nsecCovers reports whether name falls strictly between the owner and
+// next name of n in canonical order.
+func nsecCovers(n *dns.NSEC, name string) bool {
+	if canonicalCompare(n.Hdr.Name, name) >= 0 {
+		return false
+	}
+	if canonicalCompare(n.Hdr.Name, n.NextDomain) < 0 {
+		return canonicalCompare(name, n.NextDomain) < 0
+	}
+	// The last NSEC in a zone points back at the apex.
+	return dns.IsSubDomain(n.NextDomain, name)
+}
+
+// nsecClosestEncloser returns the closest encloser of name, the deepest
+// existing ancestor, shown by an NSEC covering name (RFC 4035 5.4).
+func nsecClosestEncloser(name string, nsecs []*dns.NSEC) (string, bool) {
+	for _, n := range nsecs {
+		if !nsecCovers(n, name) {
+			continue
+		}
+		if dns.IsSubDomain(n.Hdr.Name, name) &&
+			(isDelegation(n.TypeBitMap) || hasType(n.TypeBitMap, dns.TypeDNAME)) {
+			// An NSEC from above a zone cut says nothing about names below
+			// it.
+			continue
+		}
+		common := dns.CompareDomainName(name, n.Hdr.Name)
+		if c := dns.CompareDomainName(name, n.NextDomain); c > common {
+			common = c
+		}
+		return ancestor(name, common), true
+	}
+	return "", false
+}
+
+// nsec3ClosestEncloser finds the closest encloser proof for name (RFC 5155
+// 8.3): an NSEC3 matching an ancestor of name and one covering the next
+// closer name below it, which is returned as cover.
+func nsec3ClosestEncloser(name string, nsec3s []*dns.NSEC3) (ce string, cover *dns.NSEC3, ok bool) {
+	labels := dns.CountLabel(name)
+	for n := labels - 1; n >= 0; n-- {
+		ce := ancestor(name, n)
+		m := nsec3Matching(ce, nsec3s)
+		if m == nil {
+			continue
+		}
+		if isDelegation(m.TypeBitMap) || hasType(m.TypeBitMap, dns.TypeDNAME) {
+			return "", nil, false
+		}
+		cover := nsec3Covering(ancestor(name, n+1), nsec3s)
+		if cover == nil {
+			return "", nil, false
+		}
+		return ce, cover, true
+	}
+	return "", nil, false
+}
+
+func nsec3Matching(name string, nsec3s []*dns.NSEC3) *dns.NSEC3 {
+	for _, n := range nsec3s {
+		if n.Match(name) {
+			return n
+		}
+	}
+	return nil
+}
+
+func nsec3Covering(name string, nsec3s []*dns.NSEC3) *dns.NSEC3 {
+	for _, n := range nsec3s {
+		// Cover also accepts a hash equal to the owner's.
+		if n.Cover(name) && !n.Match(name) {
+			return n
+		}
+	}
+	return nil
+}
+
+func isDelegation(bitmap []uint16) bool {
+	return hasType(bitmap, dns.TypeNS) && !hasType(bitmap, dns.TypeSOA)
+}
+
+// ancestor returns the last n labels of name.
+func ancestor(name string, n int) string {
+	name = dns.Fqdn(name)
+	if n == 0 {
+		return "."
+	}
+	idx := dns.Split(name)
+	if n >= len(idx) {
+		return name
+	}
+	return name[idx[len(idx)-n]:]
+}
+
+func wildcardName(ce string) string {
+	if ce == "." {
+		return "*."
+	}
+	return "*." + ce
+}
+
+func equalNames(a, b string) bool {
+	return strings.EqualFold(dns.Fqdn(a), dns.Fqdn(b))
+}
+
+// canonicalCompare orders names as RFC 4034 6.1 does: label by label
+// from the root, comparing lower cased labels as byte strings.
+func canonicalCompare(a, b string) int {
+	la := dns.SplitDomainName(dns.CanonicalName(a))
+	lb := dns.SplitDomainName(dns.CanonicalName(b))
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(la[i], lb[j]); c != 0 {
+			return c
+		}
+	}
+	return len(la) - len(lb)
+}
+
+func hasType(bitmap []uint16, t uint16) bool {
+	for _, b := range bitmap {
+		if b == t {
+			return true
+		}
+	}
+	return false
+}
+
+// rrsets groups the records in section, other than signatures and the
+// OPT record, into RRsets in the order they first appear.
+func rrsets(section []dns.RR) [][]dns.RR {
+	var (
+		sets  [][]dns.RR
+		index = make(map[string]int)
+	)
+	for _, rr := range section {
+		hdr := rr.Header()
+		if hdr.Rrtype == dns.TypeRRSIG || hdr.Rrtype == dns.TypeOPT {
+			continue
+		}
+		key := fmt.Sprintf("%s/%d/%d", dns.CanonicalName(hdr.Name), hdr.Rrtype, hdr.Class)
+		if i, ok := index[key]; ok {
+			sets[i] = append(sets[i], rr)
+			continue
+		}
+		index[key] = len(sets)
+		sets = append(sets, []dns.RR{rr})
+	}
+	return sets
+}
+
+// rrsetOf returns the records of type t owned by name in section.
+func rrsetOf(section []dns.RR, name string, t uint16) []dns.RR {
+	var set []dns.RR
+	for _, rr := range section {
+		if hdr := rr.Header(); hdr.Rrtype == t && strings.EqualFold(hdr.Name, name) {
+			set = append(set, rr)
+		}
+	}
+	return set
+}
+
+// signaturesFor returns the signatures in section that cover set.
+func signaturesFor(set, section []dns.RR) []*dns.RRSIG {
+	if len(set) == 0 {
+		return nil
+	}
+	hdr := set[0].Header()
+	var sigs []*dns.RRSIG
+	for _, rr := range section {
+		sig, ok := rr.(*dns.RRSIG)
+		if ok && sig.TypeCovered == hdr.Rrtype && strings.EqualFold(sig.Hdr.Name, hdr.Name) {
+			sigs = append(sigs, sig)
+		}
+	}
+	return sigs
+}
+
+// withDO returns a copy of r that asks for DNSSEC records.
+func withDO(r *dns.Msg) *dns.Msg {
+	q := r.Copy()
+	if opt := q.IsEdns0(); opt != nil {
+		opt.SetDo()
+	} else {
+		q.SetEdns0(4096, true)
+	}
+	return q
+}
+
+// stripDNSSEC removes the DNSSEC records added to resp because the
+// upstream query asked for them, for a client r that didn't.
+func stripDNSSEC(r, resp *dns.Msg) {
+	var qtype uint16
+	if len(r.Question) > 0 {
+		qtype = r.Question[0].Qtype
+	}
+	strip := func(section []dns.RR) []dns.RR {
+		out := section[:0]
+		for _, rr := range section {
+			switch t := rr.Header().Rrtype; t {
+			case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3:
+				if t != qtype {
+					continue
+				}
+			}
+			out = append(out, rr)
+		}
+		return out
+	}
+	resp.Answer = strip(resp.Answer)
+	resp.Ns = strip(resp.Ns)
+	resp.Extra = strip(resp.Extra)
+
+	if r.IsEdns0() == nil {
+		extra := resp.Extra[:0]
+		for _, rr := range resp.Extra {
+			if rr.Header().Rrtype != dns.TypeOPT {
+				extra = append(extra, rr)
+			}
+		}
+		resp.Extra = extra
+	} else if opt := resp.IsEdns0(); opt != nil {
+		opt.SetDo(false)
+	}
+}
+
+// bogusResponse is the SERVFAIL answer to r sent in place of an upstream
+// answer that failed validation.
+func bogusResponse(r *dns.Msg, reason error) *dns.Msg {
+	resp := new(dns.Msg)
+	resp.SetRcode(r, dns.RcodeServerFailure)
+	if opt := r.IsEdns0(); opt != nil {
+		resp.SetEdns0(opt.UDPSize(), opt.Do())
+		resp.IsEdns0().Option = append(resp.IsEdns0().Option, &dns.EDNS0_EDE{
+			InfoCode:  dns.ExtendedErrorCodeDNSBogus,
+			ExtraText: reason.Error(),
+		})
+	}
+	return resp
+}
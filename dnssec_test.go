@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+)
+
+// testZone is a zone signed with a single key.
+type testZone struct {
+	name string
+	key  *dns.DNSKEY
+	priv crypto.Signer
+}
+
+func newTestZone(t *testing.T, name string) *testZone {
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: name, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     dns.ZONE | dns.SEP,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testZone{name: name, key: key, priv: priv.(crypto.Signer)}
+}
+
+// sign returns rrset followed by its signature.
+func (z *testZone) sign(t *testing.T, rrset ...dns.RR) []dns.RR {
+	hdr := rrset[0].Header()
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Name: hdr.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: hdr.Ttl},
+		KeyTag:     z.key.KeyTag(),
+		SignerName: z.name,
+		Algorithm:  z.key.Algorithm,
+		Inception:  uint32(time.Now().Add(-time.Hour).Unix()),
+		Expiration: uint32(time.Now().Add(time.Hour).Unix()),
+	}
+	if err := sig.Sign(z.priv, rrset); err != nil {
+		t.Fatal(err)
+	}
+	return append(rrset, sig)
+}
+
+// expand returns records signed at the wildcard *.zone renamed to name,
+// as a server synthesizes them from the wildcard.
+func (z *testZone) expand(t *testing.T, name string, rrset ...dns.RR) []dns.RR {
+	signed := z.sign(t, rrset...)
+	for _, rr := range signed {
+		rr.Header().Name = name
+	}
+	return signed
+}
+
+func (z *testZone) ds() *dns.DS {
+	return z.key.ToDS(dns.SHA256)
+}
+
+func testRR(t *testing.T, s string) dns.RR {
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rr
+}
+
+func TestDNSSECValidation(t *testing.T) {
+	root := newTestZone(t, ".")
+	example := newTestZone(t, "example.")
+	forger := newTestZone(t, "example.")
+
+	exampleDS := example.ds()
+	exampleDS.Hdr.Ttl = 3600
+
+	answers := map[string][]dns.RR{
+		"./DNSKEY":               root.sign(t, root.key),
+		"example./DS":            root.sign(t, exampleDS),
+		"example./DNSKEY":        example.sign(t, example.key),
+		"www.example./A":         example.sign(t, testRR(t, "www.example. 300 IN A 192.0.2.1")),
+		"evil.example./A":        forger.sign(t, testRR(t, "evil.example. 300 IN A 192.0.2.66")),
+		"bare.example./A":        {testRR(t, "bare.example. 300 IN A 192.0.2.2")},
+		"wild.example./A":        example.expand(t, "wild.example.", testRR(t, "*.example. 300 IN A 192.0.2.1")),
+		"wildnoproof.example./A": example.expand(t, "wildnoproof.example.", testRR(t, "*.example. 300 IN A 192.0.2.1")),
+		"a.www.example./A":       example.expand(t, "a.www.example.", testRR(t, "*.example. 300 IN A 192.0.2.1")),
+		"host.unsigned.example./A": {
+			testRR(t, "host.unsigned.example. 300 IN A 192.0.2.3"),
+		},
+	}
+	nsec := func(s string) []dns.RR {
+		return example.sign(t, testRR(t, s))
+	}
+	apexNSEC := nsec("example. 300 IN NSEC a.example. SOA NS RRSIG NSEC DNSKEY")
+	mailNSEC := nsec("mail.example. 300 IN NSEC www.example. MX RRSIG NSEC")
+	unrelatedNSEC := nsec("aaa.example. 300 IN NSEC abb.example. A RRSIG NSEC")
+	authority := map[string][]dns.RR{
+		"unsigned.example./DS": append(
+			example.sign(t, testRR(t, "example. 300 IN SOA ns.example. admin.example. 1 3600 600 86400 300")),
+			example.sign(t, testRR(t, "unsigned.example. 300 IN NSEC z.example. NS RRSIG NSEC"))...),
+		// mail.example. covers nx.example. and the apex NSEC covers the
+		// wildcard *.example.
+		"nx.example./A": append(append([]dns.RR{}, mailNSEC...), apexNSEC...),
+		// No proof that *.example. doesn't exist.
+		"nowild.example./A": mailNSEC,
+		// Signed, but about names nowhere near the query.
+		"forged.example./A": unrelatedNSEC,
+		"nodata.example./A": unrelatedNSEC,
+		"mail.example./A":   mailNSEC,
+		// mail.example. covers wild.example., the next closer name.
+		"wild.example./A": mailNSEC,
+		// mail.example. covers a.www.example. but not www.example., which
+		// exists.
+		"a.www.example./A": mailNSEC,
+	}
+	rcodes := map[string]int{
+		"nx.example./A":     dns.RcodeNameError,
+		"nowild.example./A": dns.RcodeNameError,
+		"forged.example./A": dns.RcodeNameError,
+	}
+
+	backend := exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		q := m.Question[0]
+		if opt := m.IsEdns0(); opt == nil || !opt.Do() {
+			return nil, 0, fmt.Errorf("query for %s without DO bit", q.Name)
+		}
+		key := fmt.Sprintf("%s/%s", q.Name, dns.Type(q.Qtype))
+		resp := new(dns.Msg)
+		resp.SetReply(m)
+		resp.AuthenticatedData = true
+		resp.Rcode = rcodes[key]
+		resp.Answer = answers[key]
+		resp.Ns = authority[key]
+		return resp, 0, nil
+	})
+
+	s := newTestServer(conf.Config_InOrder, backend)
+	rootDS := root.ds()
+	v, err := newValidator(&conf.Dnssec{TrustAnchors: []string{rootDS.String()}}, s.dnssecLookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.validator = v
+
+	checks := []struct {
+		name  string
+		do    bool
+		rcode int
+		ad    bool
+	}{
+		{name: "www.example.", do: true, rcode: dns.RcodeSuccess, ad: true},
+		{name: "www.example.", do: false, rcode: dns.RcodeSuccess},
+		{name: "evil.example.", do: true, rcode: dns.RcodeServerFailure},
+		{name: "bare.example.", do: true, rcode: dns.RcodeServerFailure},
+		{name: "host.unsigned.example.", do: false, rcode: dns.RcodeSuccess},
+		{name: "nx.example.", do: true, rcode: dns.RcodeNameError, ad: true},
+		{name: "nowild.example.", do: true, rcode: dns.RcodeServerFailure},
+		{name: "forged.example.", do: true, rcode: dns.RcodeServerFailure},
+		{name: "nodata.example.", do: true, rcode: dns.RcodeServerFailure},
+		{name: "mail.example.", do: true, rcode: dns.RcodeSuccess, ad: true},
+		{name: "wild.example.", do: true, rcode: dns.RcodeSuccess, ad: true},
+		{name: "wildnoproof.example.", do: true, rcode: dns.RcodeServerFailure},
+		{name: "a.www.example.", do: true, rcode: dns.RcodeServerFailure},
+	}
+
+	for _, check := range checks {
+		req := new(dns.Msg)
+		req.SetQuestion(check.name, dns.TypeA)
+		if check.do {
+			req.SetEdns0(1232, true)
+		}
+		w := &recorder{}
+		s.handleRequest(w, req)
+
+		resp := w.msg
+		if resp == nil {
+			t.Errorf("%s do=%t: no response", check.name, check.do)
+			continue
+		}
+		if resp.Rcode != check.rcode {
+			t.Errorf("%s do=%t: rcode %s, expected %s", check.name, check.do, dns.RcodeToString[resp.Rcode], dns.RcodeToString[check.rcode])
+		}
+		if resp.AuthenticatedData != check.ad {
+			t.Errorf("%s do=%t: AD=%t, expected %t", check.name, check.do, resp.AuthenticatedData, check.ad)
+		}
+		if check.rcode != dns.RcodeSuccess {
+			continue
+		}
+		var sigs int
+		for _, rr := range resp.Answer {
+			switch rr := rr.(type) {
+			case *dns.RRSIG:
+				sigs++
+			case *dns.A:
+				if !rr.A.Equal(net.ParseIP("192.0.2.1")) && !rr.A.Equal(net.ParseIP("192.0.2.3")) {
+					t.Errorf("%s: unexpected answer %s", check.name, rr)
+				}
+			}
+		}
+		if check.do && sigs == 0 && len(resp.Answer) > 0 {
+			t.Errorf("%s: no signatures returned to a DO client", check.name)
+		}
+		if !check.do && sigs > 0 {
+			t.Errorf("%s: signatures returned to a client without DO", check.name)
+		}
+	}
+}
+
+func TestNSEC3Denial(t *testing.T) {
+	// A zone holding only example. and mail.example.; its two NSEC3
+	// records form a ring over their hashes.
+	ring := func(flags uint8) []dns.RR {
+		apex := dns.HashName("example.", dns.SHA1, 0, "")
+		mail := dns.HashName("mail.example.", dns.SHA1, 0, "")
+		rr := func(owner, next string, types ...uint16) dns.RR {
+			return &dns.NSEC3{
+				Hdr:        dns.RR_Header{Name: owner + ".example.", Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: 300},
+				Hash:       dns.SHA1,
+				Flags:      flags,
+				NextDomain: next,
+				TypeBitMap: types,
+			}
+		}
+		return []dns.RR{
+			rr(apex, mail, dns.TypeSOA, dns.TypeNS, dns.TypeRRSIG, dns.TypeDNSKEY, dns.TypeNSEC3PARAM),
+			rr(mail, apex, dns.TypeMX, dns.TypeRRSIG),
+		}
+	}
+	full := ring(0)
+
+	checks := []struct {
+		desc     string
+		q        dns.Question
+		rcode    int
+		denial   []dns.RR
+		ok       bool
+		insecure bool
+	}{
+		{"nxdomain", dns.Question{Name: "nx.example.", Qtype: dns.TypeA}, dns.RcodeNameError, full, true, false},
+		{"nxdomain for existing name", dns.Question{Name: "mail.example.", Qtype: dns.TypeA}, dns.RcodeNameError, full, false, false},
+		{"nxdomain without closest encloser", dns.Question{Name: "nx.example.", Qtype: dns.TypeA}, dns.RcodeNameError, full[1:], false, false},
+		{"nodata", dns.Question{Name: "mail.example.", Qtype: dns.TypeA}, dns.RcodeSuccess, full, true, false},
+		{"nodata for existing type", dns.Question{Name: "mail.example.", Qtype: dns.TypeMX}, dns.RcodeSuccess, full, false, false},
+		{"nodata for missing name", dns.Question{Name: "nx.example.", Qtype: dns.TypeA}, dns.RcodeSuccess, full, false, false},
+		{"opt-out DS", dns.Question{Name: "sub.example.", Qtype: dns.TypeDS}, dns.RcodeSuccess, ring(1), true, true},
+		{"opt-out nxdomain", dns.Question{Name: "nx.example.", Qtype: dns.TypeA}, dns.RcodeNameError, ring(1), true, true},
+	}
+	for _, check := range checks {
+		insecure, err := checkDenial(check.q, check.rcode, check.denial)
+		if (err == nil) != check.ok {
+			t.Errorf("%s: err=%v, expected ok=%t", check.desc, err, check.ok)
+		}
+		if err == nil && insecure != check.insecure {
+			t.Errorf("%s: insecure=%t, expected %t", check.desc, insecure, check.insecure)
+		}
+	}
+
+	if !insecureDelegation("sub.example.", ring(1)) {
+		t.Error("opt-out span covering sub.example. not accepted as an insecure delegation")
+	}
+	if insecureDelegation("sub.example.", full) {
+		t.Error("span without opt-out accepted as an insecure delegation")
+	}
+}
@@ -0,0 +1,1577 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: dnstap.proto
+
+package dnstap
+
+import (
+	encoding_binary "encoding/binary"
+	fmt "fmt"
+	_ "github.com/gogo/protobuf/gogoproto"
+	github_com_gogo_protobuf_proto "github.com/gogo/protobuf/proto"
+	proto "github.com/gogo/protobuf/proto"
+	io "io"
+	math "math"
+	math_bits "math/bits"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+// SocketFamily: the network protocol family of a socket. This specifies how
+// to interpret "network address" fields.
+type SocketFamily int32
+
+const (
+	SocketFamily_INET  SocketFamily = 1
+	SocketFamily_INET6 SocketFamily = 2
+)
+
+var SocketFamily_name = map[int32]string{
+	1: "INET",
+	2: "INET6",
+}
+
+var SocketFamily_value = map[string]int32{
+	"INET":  1,
+	"INET6": 2,
+}
+
+func (x SocketFamily) Enum() *SocketFamily {
+	p := new(SocketFamily)
+	*p = x
+	return p
+}
+
+func (x SocketFamily) String() string {
+	return proto.EnumName(SocketFamily_name, int32(x))
+}
+
+func (x *SocketFamily) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(SocketFamily_value, data, "SocketFamily")
+	if err != nil {
+		return err
+	}
+	*x = SocketFamily(value)
+	return nil
+}
+
+func (SocketFamily) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_0f06582ff84ec462, []int{0}
+}
+
+// SocketProtocol: the protocol used to transport a DNS message.
+type SocketProtocol int32
+
+const (
+	SocketProtocol_UDP SocketProtocol = 1
+	SocketProtocol_TCP SocketProtocol = 2
+	SocketProtocol_DOT SocketProtocol = 3
+	SocketProtocol_DOH SocketProtocol = 4
+)
+
+var SocketProtocol_name = map[int32]string{
+	1: "UDP",
+	2: "TCP",
+	3: "DOT",
+	4: "DOH",
+}
+
+var SocketProtocol_value = map[string]int32{
+	"UDP": 1,
+	"TCP": 2,
+	"DOT": 3,
+	"DOH": 4,
+}
+
+func (x SocketProtocol) Enum() *SocketProtocol {
+	p := new(SocketProtocol)
+	*p = x
+	return p
+}
+
+func (x SocketProtocol) String() string {
+	return proto.EnumName(SocketProtocol_name, int32(x))
+}
+
+func (x *SocketProtocol) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(SocketProtocol_value, data, "SocketProtocol")
+	if err != nil {
+		return err
+	}
+	*x = SocketProtocol(value)
+	return nil
+}
+
+func (SocketProtocol) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_0f06582ff84ec462, []int{1}
+}
+
+// Identifies which field below is filled in.
+type Dnstap_Type int32
+
+const (
+	Dnstap_MESSAGE Dnstap_Type = 1
+)
+
+var Dnstap_Type_name = map[int32]string{
+	1: "MESSAGE",
+}
+
+var Dnstap_Type_value = map[string]int32{
+	"MESSAGE": 1,
+}
+
+func (x Dnstap_Type) Enum() *Dnstap_Type {
+	p := new(Dnstap_Type)
+	*p = x
+	return p
+}
+
+func (x Dnstap_Type) String() string {
+	return proto.EnumName(Dnstap_Type_name, int32(x))
+}
+
+func (x *Dnstap_Type) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(Dnstap_Type_value, data, "Dnstap_Type")
+	if err != nil {
+		return err
+	}
+	*x = Dnstap_Type(value)
+	return nil
+}
+
+func (Dnstap_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_0f06582ff84ec462, []int{0, 0}
+}
+
+type Message_Type int32
+
+const (
+	// AUTH_QUERY is a DNS query message received from a resolver by an
+	// authoritative name server, from the perspective of the authoritative
+	// name server.
+	Message_AUTH_QUERY Message_Type = 1
+	// AUTH_RESPONSE is a DNS response message sent from an authoritative
+	// name server to a resolver, from the perspective of the authoritative
+	// name server.
+	Message_AUTH_RESPONSE Message_Type = 2
+	// RESOLVER_QUERY is a DNS query message sent from a resolver to an
+	// authoritative name server, from the perspective of the resolver.
+	// Resolvers typically clear the RD (recursion desired) bit when
+	// sending queries.
+	Message_RESOLVER_QUERY Message_Type = 3
+	// RESOLVER_RESPONSE is a DNS response message received from an
+	// authoritative name server by a resolver, from the perspective of
+	// the resolver.
+	Message_RESOLVER_RESPONSE Message_Type = 4
+	// CLIENT_QUERY is a DNS query message sent from a client to a DNS
+	// server which is expected to perform further recursion, from the
+	// perspective of the DNS server. The client may be a stub resolver or
+	// forwarder or some other type of software which typically sets the RD
+	// (recursion desired) bit when querying the DNS server. The DNS server
+	// may be a simple forwarding proxy or it may be a full recursive
+	// resolver.
+	Message_CLIENT_QUERY Message_Type = 5
+	// CLIENT_RESPONSE is a DNS response message sent from a DNS server to
+	// a client, from the perspective of the DNS server. The DNS server
+	// typically sets the RA (recursion available) bit when responding.
+	Message_CLIENT_RESPONSE Message_Type = 6
+	// FORWARDER_QUERY is a DNS query message sent from a downstream DNS
+	// server to an upstream DNS server which is expected to perform
+	// further recursion, from the perspective of the downstream DNS
+	// server.
+	Message_FORWARDER_QUERY Message_Type = 7
+	// FORWARDER_RESPONSE is a DNS response message sent from an upstream
+	// DNS server performing recursion to a downstream DNS server, from the
+	// perspective of the downstream DNS server.
+	Message_FORWARDER_RESPONSE Message_Type = 8
+	// STUB_QUERY is a DNS query message sent from a stub resolver to a DNS
+	// server, from the perspective of the stub resolver.
+	Message_STUB_QUERY Message_Type = 9
+	// STUB_RESPONSE is a DNS response message sent from a DNS server to a
+	// stub resolver, from the perspective of the stub resolver.
+	Message_STUB_RESPONSE Message_Type = 10
+	// TOOL_QUERY is a DNS query message sent from a DNS software tool to a
+	// DNS server, from the perspective of the tool.
+	Message_TOOL_QUERY Message_Type = 11
+	// TOOL_RESPONSE is a DNS response message received by a DNS software
+	// tool from a DNS server, from the perspective of the tool.
+	Message_TOOL_RESPONSE Message_Type = 12
+	// UPDATE_QUERY is a DNS update query message received from a resolver
+	// by an authoritative name server, from the perspective of the
+	// authoritative name server.
+	Message_UPDATE_QUERY Message_Type = 13
+	// UPDATE_RESPONSE is a DNS update response message sent from an
+	// authoritative name server to a resolver, from the perspective of the
+	// authoritative name server.
+	Message_UPDATE_RESPONSE Message_Type = 14
+)
+
+var Message_Type_name = map[int32]string{
+	1:  "AUTH_QUERY",
+	2:  "AUTH_RESPONSE",
+	3:  "RESOLVER_QUERY",
+	4:  "RESOLVER_RESPONSE",
+	5:  "CLIENT_QUERY",
+	6:  "CLIENT_RESPONSE",
+	7:  "FORWARDER_QUERY",
+	8:  "FORWARDER_RESPONSE",
+	9:  "STUB_QUERY",
+	10: "STUB_RESPONSE",
+	11: "TOOL_QUERY",
+	12: "TOOL_RESPONSE",
+	13: "UPDATE_QUERY",
+	14: "UPDATE_RESPONSE",
+}
+
+var Message_Type_value = map[string]int32{
+	"AUTH_QUERY":         1,
+	"AUTH_RESPONSE":      2,
+	"RESOLVER_QUERY":     3,
+	"RESOLVER_RESPONSE":  4,
+	"CLIENT_QUERY":       5,
+	"CLIENT_RESPONSE":    6,
+	"FORWARDER_QUERY":    7,
+	"FORWARDER_RESPONSE": 8,
+	"STUB_QUERY":         9,
+	"STUB_RESPONSE":      10,
+	"TOOL_QUERY":         11,
+	"TOOL_RESPONSE":      12,
+	"UPDATE_QUERY":       13,
+	"UPDATE_RESPONSE":    14,
+}
+
+func (x Message_Type) Enum() *Message_Type {
+	p := new(Message_Type)
+	*p = x
+	return p
+}
+
+func (x Message_Type) String() string {
+	return proto.EnumName(Message_Type_name, int32(x))
+}
+
+func (x *Message_Type) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(Message_Type_value, data, "Message_Type")
+	if err != nil {
+		return err
+	}
+	*x = Message_Type(value)
+	return nil
+}
+
+func (Message_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_0f06582ff84ec462, []int{1, 0}
+}
+
+// "Dnstap": this is the top-level dnstap type, which is a "union" type that
+// contains other kinds of dnstap payloads, although currently only one type
+// of dnstap payload is defined.
+// See: https://developers.google.com/protocol-buffers/docs/techniques#union
+type Dnstap struct {
+	// DNS server identity.
+	// If enabled, this is the identity string of the DNS server which generated
+	// this message. Typically this would be the same string as returned by an
+	// "NSID" (RFC 5001) query.
+	Identity []byte `protobuf:"bytes,1,opt,name=identity" json:"identity,omitempty"`
+	// DNS server version.
+	// If enabled, this is the version string of the DNS server which generated
+	// this message. Typically this would be the same string as returned by a
+	// "version.bind" query.
+	Version []byte `protobuf:"bytes,2,opt,name=version" json:"version,omitempty"`
+	// Extra data for this payload.
+	// This field can be used for adding an arbitrary byte-string annotation to
+	// the payload. No encoding or interpretation is applied or enforced.
+	Extra []byte       `protobuf:"bytes,3,opt,name=extra" json:"extra,omitempty"`
+	Type  *Dnstap_Type `protobuf:"varint,15,req,name=type,enum=dnstap.Dnstap_Type" json:"type,omitempty"`
+	// One of the following will be filled in.
+	Message              *Message `protobuf:"bytes,14,opt,name=message" json:"message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Dnstap) Reset()         { *m = Dnstap{} }
+func (m *Dnstap) String() string { return proto.CompactTextString(m) }
+func (*Dnstap) ProtoMessage()    {}
+func (*Dnstap) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0f06582ff84ec462, []int{0}
+}
+func (m *Dnstap) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Dnstap) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Dnstap.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Dnstap) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Dnstap.Merge(m, src)
+}
+func (m *Dnstap) XXX_Size() int {
+	return m.Size()
+}
+func (m *Dnstap) XXX_DiscardUnknown() {
+	xxx_messageInfo_Dnstap.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Dnstap proto.InternalMessageInfo
+
+func (m *Dnstap) GetIdentity() []byte {
+	if m != nil {
+		return m.Identity
+	}
+	return nil
+}
+
+func (m *Dnstap) GetVersion() []byte {
+	if m != nil {
+		return m.Version
+	}
+	return nil
+}
+
+func (m *Dnstap) GetExtra() []byte {
+	if m != nil {
+		return m.Extra
+	}
+	return nil
+}
+
+func (m *Dnstap) GetType() Dnstap_Type {
+	if m != nil && m.Type != nil {
+		return *m.Type
+	}
+	return Dnstap_MESSAGE
+}
+
+func (m *Dnstap) GetMessage() *Message {
+	if m != nil {
+		return m.Message
+	}
+	return nil
+}
+
+// Message: a wire-format (RFC 1035 section 4) DNS message and associated
+// metadata. Applications generating "Message" payloads should follow
+// certain requirements based on the MessageType, see below.
+type Message struct {
+	// One of the Type values described above.
+	Type *Message_Type `protobuf:"varint,1,req,name=type,enum=dnstap.Message_Type" json:"type,omitempty"`
+	// One of the SocketFamily values described above.
+	SocketFamily *SocketFamily `protobuf:"varint,2,opt,name=socket_family,json=socketFamily,enum=dnstap.SocketFamily" json:"socket_family,omitempty"`
+	// One of the SocketProtocol values described above.
+	SocketProtocol *SocketProtocol `protobuf:"varint,3,opt,name=socket_protocol,json=socketProtocol,enum=dnstap.SocketProtocol" json:"socket_protocol,omitempty"`
+	// The network address of the message initiator.
+	// For SocketFamily INET, this field is 4 octets (IPv4 address).
+	// For SocketFamily INET6, this field is 16 octets (IPv6 address).
+	QueryAddress []byte `protobuf:"bytes,4,opt,name=query_address,json=queryAddress" json:"query_address,omitempty"`
+	// The network address of the message responder.
+	// For SocketFamily INET, this field is 4 octets (IPv4 address).
+	// For SocketFamily INET6, this field is 16 octets (IPv6 address).
+	ResponseAddress []byte `protobuf:"bytes,5,opt,name=response_address,json=responseAddress" json:"response_address,omitempty"`
+	// The transport port of the message initiator.
+	// This is a 16-bit UDP or TCP port number, depending on SocketProtocol.
+	QueryPort *uint32 `protobuf:"varint,6,opt,name=query_port,json=queryPort" json:"query_port,omitempty"`
+	// The transport port of the message responder.
+	// This is a 16-bit UDP or TCP port number, depending on SocketProtocol.
+	ResponsePort *uint32 `protobuf:"varint,7,opt,name=response_port,json=responsePort" json:"response_port,omitempty"`
+	// The time at which the DNS query message was sent or received, depending
+	// on whether this is an AUTH_QUERY, RESOLVER_QUERY, or CLIENT_QUERY.
+	// This is the number of seconds since the UNIX epoch.
+	QueryTimeSec *uint64 `protobuf:"varint,8,opt,name=query_time_sec,json=queryTimeSec" json:"query_time_sec,omitempty"`
+	// The time at which the DNS query message was sent or received.
+	// This is the seconds fraction, expressed as a count of nanoseconds.
+	QueryTimeNsec *uint32 `protobuf:"fixed32,9,opt,name=query_time_nsec,json=queryTimeNsec" json:"query_time_nsec,omitempty"`
+	// The initiator's original wire-format DNS query message, verbatim.
+	QueryMessage []byte `protobuf:"bytes,10,opt,name=query_message,json=queryMessage" json:"query_message,omitempty"`
+	// The "zone" or "bailiwick" pertaining to the DNS query message.
+	// This is a wire-format DNS domain name.
+	QueryZone []byte `protobuf:"bytes,11,opt,name=query_zone,json=queryZone" json:"query_zone,omitempty"`
+	// The time at which the DNS response message was sent or received,
+	// depending on whether this is an AUTH_RESPONSE, RESOLVER_RESPONSE, or
+	// CLIENT_RESPONSE.
+	// This is the number of seconds since the UNIX epoch.
+	ResponseTimeSec *uint64 `protobuf:"varint,12,opt,name=response_time_sec,json=responseTimeSec" json:"response_time_sec,omitempty"`
+	// The time at which the DNS response message was sent or received.
+	// This is the seconds fraction, expressed as a count of nanoseconds.
+	ResponseTimeNsec *uint32 `protobuf:"fixed32,13,opt,name=response_time_nsec,json=responseTimeNsec" json:"response_time_nsec,omitempty"`
+	// The responder's original wire-format DNS response message, verbatim.
+	ResponseMessage      []byte   `protobuf:"bytes,14,opt,name=response_message,json=responseMessage" json:"response_message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Message) Reset()         { *m = Message{} }
+func (m *Message) String() string { return proto.CompactTextString(m) }
+func (*Message) ProtoMessage()    {}
+func (*Message) Descriptor() ([]byte, []int) {
+	return fileDescriptor_0f06582ff84ec462, []int{1}
+}
+func (m *Message) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Message) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Message.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Message) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Message.Merge(m, src)
+}
+func (m *Message) XXX_Size() int {
+	return m.Size()
+}
+func (m *Message) XXX_DiscardUnknown() {
+	xxx_messageInfo_Message.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Message proto.InternalMessageInfo
+
+func (m *Message) GetType() Message_Type {
+	if m != nil && m.Type != nil {
+		return *m.Type
+	}
+	return Message_AUTH_QUERY
+}
+
+func (m *Message) GetSocketFamily() SocketFamily {
+	if m != nil && m.SocketFamily != nil {
+		return *m.SocketFamily
+	}
+	return SocketFamily_INET
+}
+
+func (m *Message) GetSocketProtocol() SocketProtocol {
+	if m != nil && m.SocketProtocol != nil {
+		return *m.SocketProtocol
+	}
+	return SocketProtocol_UDP
+}
+
+func (m *Message) GetQueryAddress() []byte {
+	if m != nil {
+		return m.QueryAddress
+	}
+	return nil
+}
+
+func (m *Message) GetResponseAddress() []byte {
+	if m != nil {
+		return m.ResponseAddress
+	}
+	return nil
+}
+
+func (m *Message) GetQueryPort() uint32 {
+	if m != nil && m.QueryPort != nil {
+		return *m.QueryPort
+	}
+	return 0
+}
+
+func (m *Message) GetResponsePort() uint32 {
+	if m != nil && m.ResponsePort != nil {
+		return *m.ResponsePort
+	}
+	return 0
+}
+
+func (m *Message) GetQueryTimeSec() uint64 {
+	if m != nil && m.QueryTimeSec != nil {
+		return *m.QueryTimeSec
+	}
+	return 0
+}
+
+func (m *Message) GetQueryTimeNsec() uint32 {
+	if m != nil && m.QueryTimeNsec != nil {
+		return *m.QueryTimeNsec
+	}
+	return 0
+}
+
+func (m *Message) GetQueryMessage() []byte {
+	if m != nil {
+		return m.QueryMessage
+	}
+	return nil
+}
+
+func (m *Message) GetQueryZone() []byte {
+	if m != nil {
+		return m.QueryZone
+	}
+	return nil
+}
+
+func (m *Message) GetResponseTimeSec() uint64 {
+	if m != nil && m.ResponseTimeSec != nil {
+		return *m.ResponseTimeSec
+	}
+	return 0
+}
+
+func (m *Message) GetResponseTimeNsec() uint32 {
+	if m != nil && m.ResponseTimeNsec != nil {
+		return *m.ResponseTimeNsec
+	}
+	return 0
+}
+
+func (m *Message) GetResponseMessage() []byte {
+	if m != nil {
+		return m.ResponseMessage
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("dnstap.SocketFamily", SocketFamily_name, SocketFamily_value)
+	proto.RegisterEnum("dnstap.SocketProtocol", SocketProtocol_name, SocketProtocol_value)
+	proto.RegisterEnum("dnstap.Dnstap_Type", Dnstap_Type_name, Dnstap_Type_value)
+	proto.RegisterEnum("dnstap.Message_Type", Message_Type_name, Message_Type_value)
+	proto.RegisterType((*Dnstap)(nil), "dnstap.Dnstap")
+	proto.RegisterType((*Message)(nil), "dnstap.Message")
+}
+
+func init() { proto.RegisterFile("dnstap.proto", fileDescriptor_0f06582ff84ec462) }
+
+var fileDescriptor_0f06582ff84ec462 = []byte{
+	// 631 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x5c, 0x93, 0x51, 0x6e, 0xd3, 0x40,
+	0x10, 0x86, 0xb5, 0x89, 0x13, 0x27, 0x13, 0xdb, 0x71, 0xb7, 0xa5, 0xb2, 0x2a, 0x51, 0x45, 0x29,
+	0x02, 0xb7, 0x42, 0x45, 0xaa, 0x10, 0x12, 0x4f, 0x28, 0x6d, 0x5c, 0x5a, 0xa9, 0x4d, 0xc2, 0xda,
+	0x01, 0xc1, 0x4b, 0x14, 0x25, 0x4b, 0x15, 0xd1, 0xd8, 0xc1, 0x6b, 0x10, 0xe1, 0x1e, 0x1c, 0x87,
+	0x77, 0x1e, 0x39, 0x02, 0xea, 0x11, 0x38, 0x01, 0xda, 0xf1, 0x7a, 0xeb, 0xf0, 0x36, 0xf3, 0xcf,
+	0xb7, 0x33, 0xff, 0x8e, 0xbd, 0x60, 0xcd, 0x63, 0x91, 0x4d, 0x57, 0xc7, 0xab, 0x34, 0xc9, 0x12,
+	0x5a, 0xcf, 0xb3, 0xbd, 0x9d, 0x9b, 0xe4, 0x26, 0x41, 0xe9, 0x99, 0x8c, 0xf2, 0x6a, 0xf7, 0x27,
+	0x81, 0x7a, 0x1f, 0x01, 0xba, 0x07, 0x8d, 0xc5, 0x9c, 0xc7, 0xd9, 0x22, 0x5b, 0x7b, 0xa4, 0x43,
+	0x7c, 0x8b, 0xe9, 0x9c, 0x7a, 0x60, 0x7e, 0xe5, 0xa9, 0x58, 0x24, 0xb1, 0x57, 0xc1, 0x52, 0x91,
+	0xd2, 0x1d, 0xa8, 0xf1, 0x6f, 0x59, 0x3a, 0xf5, 0xaa, 0xa8, 0xe7, 0x09, 0x7d, 0x02, 0x46, 0xb6,
+	0x5e, 0x71, 0xaf, 0xdd, 0xa9, 0xf8, 0xce, 0xc9, 0xf6, 0xb1, 0x72, 0x94, 0x4f, 0x3a, 0x8e, 0xd6,
+	0x2b, 0xce, 0x10, 0xa0, 0x87, 0x60, 0x2e, 0xb9, 0x10, 0xd3, 0x1b, 0xee, 0x39, 0x1d, 0xe2, 0xb7,
+	0x4e, 0xda, 0x05, 0x7b, 0x9d, 0xcb, 0xac, 0xa8, 0x77, 0xb7, 0xc1, 0x90, 0x07, 0x69, 0x0b, 0xcc,
+	0xeb, 0x20, 0x0c, 0x7b, 0xaf, 0x03, 0x97, 0x74, 0xff, 0xd6, 0xc1, 0x54, 0x24, 0xf5, 0xd5, 0x50,
+	0x82, 0x43, 0x77, 0xfe, 0x6b, 0x54, 0x9e, 0xfa, 0x12, 0x6c, 0x91, 0xcc, 0x3e, 0xf1, 0x6c, 0xf2,
+	0x71, 0xba, 0x5c, 0xdc, 0xae, 0xf1, 0x52, 0xa5, 0x23, 0x21, 0x16, 0xcf, 0xb1, 0xc6, 0x2c, 0x51,
+	0xca, 0xe8, 0x2b, 0x68, 0xab, 0xa3, 0xb8, 0xc0, 0x59, 0x72, 0x8b, 0x37, 0x77, 0x4e, 0x76, 0x37,
+	0x0f, 0x8f, 0x54, 0x95, 0x39, 0x62, 0x23, 0xa7, 0x07, 0x60, 0x7f, 0xfe, 0xc2, 0xd3, 0xf5, 0x64,
+	0x3a, 0x9f, 0xa7, 0x5c, 0x08, 0xcf, 0xc0, 0xc5, 0x59, 0x28, 0xf6, 0x72, 0x8d, 0x1e, 0x82, 0x9b,
+	0x72, 0xb1, 0x4a, 0x62, 0xc1, 0x35, 0x57, 0x43, 0xae, 0x5d, 0xe8, 0x05, 0xfa, 0x10, 0x20, 0xef,
+	0xb7, 0x4a, 0xd2, 0xcc, 0xab, 0x77, 0x88, 0x6f, 0xb3, 0x26, 0x2a, 0xa3, 0x24, 0xcd, 0xe4, 0x38,
+	0xdd, 0x09, 0x09, 0x13, 0x09, 0xab, 0x10, 0x11, 0x7a, 0x04, 0x4e, 0xde, 0x23, 0x5b, 0x2c, 0xf9,
+	0x44, 0xf0, 0x99, 0xd7, 0xe8, 0x10, 0xdf, 0x50, 0xa6, 0xa2, 0xc5, 0x92, 0x87, 0x7c, 0x46, 0x1f,
+	0x43, 0xbb, 0x44, 0xc5, 0x12, 0x6b, 0x76, 0x88, 0x6f, 0x32, 0x5b, 0x63, 0x03, 0xc1, 0x67, 0xf7,
+	0x37, 0x2c, 0xbe, 0x2c, 0x94, 0x6e, 0x58, 0x7c, 0x2c, 0x6d, 0xfb, 0x7b, 0x12, 0x73, 0xaf, 0x85,
+	0x44, 0x6e, 0xfb, 0x43, 0x12, 0x73, 0x7a, 0x04, 0x5b, 0xda, 0xb6, 0x36, 0x65, 0xa1, 0x29, 0xbd,
+	0x81, 0xc2, 0xd7, 0x53, 0xa0, 0x9b, 0x2c, 0x5a, 0xb3, 0xd1, 0x9a, 0x5b, 0x86, 0xd1, 0x5d, 0x79,
+	0xb5, 0xe5, 0x5f, 0xaf, 0xb4, 0x5a, 0xe5, 0xb1, 0xfb, 0xa3, 0xa2, 0x7e, 0x39, 0x07, 0xa0, 0x37,
+	0x8e, 0x2e, 0x26, 0x6f, 0xc6, 0x01, 0x7b, 0xef, 0x12, 0xba, 0x05, 0x36, 0xe6, 0x2c, 0x08, 0x47,
+	0xc3, 0x41, 0x18, 0xb8, 0x15, 0x4a, 0xc1, 0x61, 0x41, 0x38, 0xbc, 0x7a, 0x1b, 0x30, 0x85, 0x55,
+	0xe9, 0x03, 0xd8, 0xd2, 0x9a, 0x46, 0x0d, 0xea, 0x82, 0x75, 0x76, 0x75, 0x19, 0x0c, 0x22, 0x05,
+	0xd6, 0xe8, 0x36, 0xb4, 0x95, 0xa2, 0xb1, 0xba, 0x14, 0xcf, 0x87, 0xec, 0x5d, 0x8f, 0xf5, 0x75,
+	0x4b, 0x93, 0xee, 0x02, 0xbd, 0x17, 0x35, 0xdc, 0x90, 0x0e, 0xc3, 0x68, 0x7c, 0xaa, 0xb8, 0xa6,
+	0x74, 0x88, 0xb9, 0x46, 0x40, 0x22, 0xd1, 0x70, 0x78, 0xa5, 0x90, 0x96, 0x44, 0x30, 0xd7, 0x88,
+	0x25, 0x9d, 0x8d, 0x47, 0xfd, 0x5e, 0x14, 0x28, 0xc8, 0x96, 0x26, 0x94, 0xa2, 0x31, 0xe7, 0xe8,
+	0x00, 0xac, 0xf2, 0x0b, 0xa1, 0x0d, 0x30, 0x2e, 0x07, 0x41, 0xe4, 0x12, 0xda, 0x84, 0x9a, 0x8c,
+	0x5e, 0xb8, 0x95, 0xa3, 0xe7, 0xe0, 0x6c, 0xbe, 0x04, 0x6a, 0x42, 0x75, 0xdc, 0x1f, 0xb9, 0x44,
+	0x06, 0xd1, 0xd9, 0xc8, 0xad, 0xc8, 0xa0, 0x3f, 0x8c, 0xdc, 0x6a, 0x1e, 0x5c, 0xb8, 0xc6, 0xa9,
+	0xf5, 0xeb, 0x6e, 0x9f, 0xfc, 0xbe, 0xdb, 0x27, 0x7f, 0xee, 0xf6, 0xc9, 0xbf, 0x01, 0x00, 0xdd,
+	0xeb, 0x72, 0x30, 0xca, 0x04, 0x00, 0x00,
+}
+
+func (m *Dnstap) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Dnstap) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Dnstap) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.Type == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("type")
+	} else {
+		i = encodeVarintDnstap(dAtA, i, uint64(*m.Type))
+		i--
+		dAtA[i] = 0x78
+	}
+	if m.Message != nil {
+		{
+			size, err := m.Message.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintDnstap(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x72
+	}
+	if m.Extra != nil {
+		i -= len(m.Extra)
+		copy(dAtA[i:], m.Extra)
+		i = encodeVarintDnstap(dAtA, i, uint64(len(m.Extra)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if m.Version != nil {
+		i -= len(m.Version)
+		copy(dAtA[i:], m.Version)
+		i = encodeVarintDnstap(dAtA, i, uint64(len(m.Version)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Identity != nil {
+		i -= len(m.Identity)
+		copy(dAtA[i:], m.Identity)
+		i = encodeVarintDnstap(dAtA, i, uint64(len(m.Identity)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *Message) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Message) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Message) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.XXX_unrecognized != nil {
+		i -= len(m.XXX_unrecognized)
+		copy(dAtA[i:], m.XXX_unrecognized)
+	}
+	if m.ResponseMessage != nil {
+		i -= len(m.ResponseMessage)
+		copy(dAtA[i:], m.ResponseMessage)
+		i = encodeVarintDnstap(dAtA, i, uint64(len(m.ResponseMessage)))
+		i--
+		dAtA[i] = 0x72
+	}
+	if m.ResponseTimeNsec != nil {
+		i -= 4
+		encoding_binary.LittleEndian.PutUint32(dAtA[i:], uint32(*m.ResponseTimeNsec))
+		i--
+		dAtA[i] = 0x6d
+	}
+	if m.ResponseTimeSec != nil {
+		i = encodeVarintDnstap(dAtA, i, uint64(*m.ResponseTimeSec))
+		i--
+		dAtA[i] = 0x60
+	}
+	if m.QueryZone != nil {
+		i -= len(m.QueryZone)
+		copy(dAtA[i:], m.QueryZone)
+		i = encodeVarintDnstap(dAtA, i, uint64(len(m.QueryZone)))
+		i--
+		dAtA[i] = 0x5a
+	}
+	if m.QueryMessage != nil {
+		i -= len(m.QueryMessage)
+		copy(dAtA[i:], m.QueryMessage)
+		i = encodeVarintDnstap(dAtA, i, uint64(len(m.QueryMessage)))
+		i--
+		dAtA[i] = 0x52
+	}
+	if m.QueryTimeNsec != nil {
+		i -= 4
+		encoding_binary.LittleEndian.PutUint32(dAtA[i:], uint32(*m.QueryTimeNsec))
+		i--
+		dAtA[i] = 0x4d
+	}
+	if m.QueryTimeSec != nil {
+		i = encodeVarintDnstap(dAtA, i, uint64(*m.QueryTimeSec))
+		i--
+		dAtA[i] = 0x40
+	}
+	if m.ResponsePort != nil {
+		i = encodeVarintDnstap(dAtA, i, uint64(*m.ResponsePort))
+		i--
+		dAtA[i] = 0x38
+	}
+	if m.QueryPort != nil {
+		i = encodeVarintDnstap(dAtA, i, uint64(*m.QueryPort))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.ResponseAddress != nil {
+		i -= len(m.ResponseAddress)
+		copy(dAtA[i:], m.ResponseAddress)
+		i = encodeVarintDnstap(dAtA, i, uint64(len(m.ResponseAddress)))
+		i--
+		dAtA[i] = 0x2a
+	}
+	if m.QueryAddress != nil {
+		i -= len(m.QueryAddress)
+		copy(dAtA[i:], m.QueryAddress)
+		i = encodeVarintDnstap(dAtA, i, uint64(len(m.QueryAddress)))
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.SocketProtocol != nil {
+		i = encodeVarintDnstap(dAtA, i, uint64(*m.SocketProtocol))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.SocketFamily != nil {
+		i = encodeVarintDnstap(dAtA, i, uint64(*m.SocketFamily))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.Type == nil {
+		return 0, github_com_gogo_protobuf_proto.NewRequiredNotSetError("type")
+	} else {
+		i = encodeVarintDnstap(dAtA, i, uint64(*m.Type))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintDnstap(dAtA []byte, offset int, v uint64) int {
+	offset -= sovDnstap(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *Dnstap) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Identity != nil {
+		l = len(m.Identity)
+		n += 1 + l + sovDnstap(uint64(l))
+	}
+	if m.Version != nil {
+		l = len(m.Version)
+		n += 1 + l + sovDnstap(uint64(l))
+	}
+	if m.Extra != nil {
+		l = len(m.Extra)
+		n += 1 + l + sovDnstap(uint64(l))
+	}
+	if m.Message != nil {
+		l = m.Message.Size()
+		n += 1 + l + sovDnstap(uint64(l))
+	}
+	if m.Type != nil {
+		n += 1 + sovDnstap(uint64(*m.Type))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func (m *Message) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Type != nil {
+		n += 1 + sovDnstap(uint64(*m.Type))
+	}
+	if m.SocketFamily != nil {
+		n += 1 + sovDnstap(uint64(*m.SocketFamily))
+	}
+	if m.SocketProtocol != nil {
+		n += 1 + sovDnstap(uint64(*m.SocketProtocol))
+	}
+	if m.QueryAddress != nil {
+		l = len(m.QueryAddress)
+		n += 1 + l + sovDnstap(uint64(l))
+	}
+	if m.ResponseAddress != nil {
+		l = len(m.ResponseAddress)
+		n += 1 + l + sovDnstap(uint64(l))
+	}
+	if m.QueryPort != nil {
+		n += 1 + sovDnstap(uint64(*m.QueryPort))
+	}
+	if m.ResponsePort != nil {
+		n += 1 + sovDnstap(uint64(*m.ResponsePort))
+	}
+	if m.QueryTimeSec != nil {
+		n += 1 + sovDnstap(uint64(*m.QueryTimeSec))
+	}
+	if m.QueryTimeNsec != nil {
+		n += 5
+	}
+	if m.QueryMessage != nil {
+		l = len(m.QueryMessage)
+		n += 1 + l + sovDnstap(uint64(l))
+	}
+	if m.QueryZone != nil {
+		l = len(m.QueryZone)
+		n += 1 + l + sovDnstap(uint64(l))
+	}
+	if m.ResponseTimeSec != nil {
+		n += 1 + sovDnstap(uint64(*m.ResponseTimeSec))
+	}
+	if m.ResponseTimeNsec != nil {
+		n += 5
+	}
+	if m.ResponseMessage != nil {
+		l = len(m.ResponseMessage)
+		n += 1 + l + sovDnstap(uint64(l))
+	}
+	if m.XXX_unrecognized != nil {
+		n += len(m.XXX_unrecognized)
+	}
+	return n
+}
+
+func sovDnstap(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozDnstap(x uint64) (n int) {
+	return sovDnstap(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *Dnstap) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowDnstap
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Dnstap: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Dnstap: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Identity", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Identity = append(m.Identity[:0], dAtA[iNdEx:postIndex]...)
+			if m.Identity == nil {
+				m.Identity = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Version = append(m.Version[:0], dAtA[iNdEx:postIndex]...)
+			if m.Version == nil {
+				m.Version = []byte{}
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Extra", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Extra = append(m.Extra[:0], dAtA[iNdEx:postIndex]...)
+			if m.Extra == nil {
+				m.Extra = []byte{}
+			}
+			iNdEx = postIndex
+		case 14:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Message", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Message == nil {
+				m.Message = &Message{}
+			}
+			if err := m.Message.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 15:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+			}
+			var v Dnstap_Type
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= Dnstap_Type(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Type = &v
+			hasFields[0] |= uint64(0x00000001)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipDnstap(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("type")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *Message) Unmarshal(dAtA []byte) error {
+	var hasFields [1]uint64
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowDnstap
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Message: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Message: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Type", wireType)
+			}
+			var v Message_Type
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= Message_Type(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Type = &v
+			hasFields[0] |= uint64(0x00000001)
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SocketFamily", wireType)
+			}
+			var v SocketFamily
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= SocketFamily(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SocketFamily = &v
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field SocketProtocol", wireType)
+			}
+			var v SocketProtocol
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= SocketProtocol(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.SocketProtocol = &v
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueryAddress", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.QueryAddress = append(m.QueryAddress[:0], dAtA[iNdEx:postIndex]...)
+			if m.QueryAddress == nil {
+				m.QueryAddress = []byte{}
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ResponseAddress", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ResponseAddress = append(m.ResponseAddress[:0], dAtA[iNdEx:postIndex]...)
+			if m.ResponseAddress == nil {
+				m.ResponseAddress = []byte{}
+			}
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueryPort", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.QueryPort = &v
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ResponsePort", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ResponsePort = &v
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueryTimeSec", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.QueryTimeSec = &v
+		case 9:
+			if wireType != 5 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueryTimeNsec", wireType)
+			}
+			var v uint32
+			if (iNdEx + 4) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint32(encoding_binary.LittleEndian.Uint32(dAtA[iNdEx:]))
+			iNdEx += 4
+			m.QueryTimeNsec = &v
+		case 10:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueryMessage", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.QueryMessage = append(m.QueryMessage[:0], dAtA[iNdEx:postIndex]...)
+			if m.QueryMessage == nil {
+				m.QueryMessage = []byte{}
+			}
+			iNdEx = postIndex
+		case 11:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field QueryZone", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.QueryZone = append(m.QueryZone[:0], dAtA[iNdEx:postIndex]...)
+			if m.QueryZone == nil {
+				m.QueryZone = []byte{}
+			}
+			iNdEx = postIndex
+		case 12:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ResponseTimeSec", wireType)
+			}
+			var v uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.ResponseTimeSec = &v
+		case 13:
+			if wireType != 5 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ResponseTimeNsec", wireType)
+			}
+			var v uint32
+			if (iNdEx + 4) > l {
+				return io.ErrUnexpectedEOF
+			}
+			v = uint32(encoding_binary.LittleEndian.Uint32(dAtA[iNdEx:]))
+			iNdEx += 4
+			m.ResponseTimeNsec = &v
+		case 14:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ResponseMessage", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ResponseMessage = append(m.ResponseMessage[:0], dAtA[iNdEx:postIndex]...)
+			if m.ResponseMessage == nil {
+				m.ResponseMessage = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipDnstap(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthDnstap
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XXX_unrecognized = append(m.XXX_unrecognized, dAtA[iNdEx:iNdEx+skippy]...)
+			iNdEx += skippy
+		}
+	}
+	if hasFields[0]&uint64(0x00000001) == 0 {
+		return github_com_gogo_protobuf_proto.NewRequiredNotSetError("type")
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipDnstap(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowDnstap
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowDnstap
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthDnstap
+			}
+			iNdEx += length
+		case 3:
+			depth++
+		case 4:
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupDnstap
+			}
+			depth--
+		case 5:
+			iNdEx += 4
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthDnstap
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+var (
+	ErrInvalidLengthDnstap        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowDnstap          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupDnstap = fmt.Errorf("proto: unexpected end of group")
+)
@@ -0,0 +1,187 @@
+// Package dnstap contains the dnstap message types and a minimal Frame
+// Streams writer for sending them to a collector.
+package dnstap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ContentType identifies dnstap payloads in the Frame Streams handshake.
+const ContentType = "protobuf:dnstap.Dnstap"
+
+const (
+	controlAccept = 0x01
+	controlStart  = 0x02
+	controlStop   = 0x03
+	controlReady  = 0x04
+	controlFinish = 0x05
+
+	controlFieldContentType = 0x01
+
+	maxControlFrameLen = 512
+)
+
+// Writer writes Frame Streams data frames. A bidirectional Writer
+// performs the READY/ACCEPT handshake expected by socket collectors; a
+// unidirectional one only writes START and STOP frames, as used for
+// files.
+type Writer struct {
+	w             *bufio.Writer
+	r             io.Reader
+	bidirectional bool
+}
+
+// NewWriter starts a unidirectional stream on w.
+func NewWriter(w io.Writer) (*Writer, error) {
+	fw := &Writer{w: bufio.NewWriter(w)}
+	if err := fw.writeControl(controlStart, ContentType); err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
+
+// NewBidirectionalWriter performs the Frame Streams handshake on rw and
+// starts a stream.
+func NewBidirectionalWriter(rw io.ReadWriter) (*Writer, error) {
+	fw := &Writer{
+		w:             bufio.NewWriter(rw),
+		r:             rw,
+		bidirectional: true,
+	}
+
+	if err := fw.writeControl(controlReady, ContentType); err != nil {
+		return nil, err
+	}
+	if err := fw.w.Flush(); err != nil {
+		return nil, err
+	}
+	typ, contentTypes, err := readControl(fw.r)
+	if err != nil {
+		return nil, err
+	}
+	if typ != controlAccept {
+		return nil, fmt.Errorf("dnstap: expected ACCEPT control frame, got type %d", typ)
+	}
+	if !contains(contentTypes, ContentType) {
+		return nil, fmt.Errorf("dnstap: collector does not accept %q", ContentType)
+	}
+
+	if err := fw.writeControl(controlStart, ContentType); err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
+
+// WriteFrame writes b as a single data frame. Frames are buffered until
+// Flush or Close.
+func (fw *Writer) WriteFrame(b []byte) error {
+	if len(b) == 0 {
+		return errors.New("dnstap: empty frame")
+	}
+	var hdr [4]byte
+	binary.BigEndian.PutUint32(hdr[:], uint32(len(b)))
+	if _, err := fw.w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := fw.w.Write(b)
+	return err
+}
+
+func (fw *Writer) Flush() error {
+	return fw.w.Flush()
+}
+
+// Close writes a STOP frame and, for bidirectional streams, waits for
+// the collector's FINISH. It does not close the underlying connection.
+func (fw *Writer) Close() error {
+	if err := fw.writeControl(controlStop, ""); err != nil {
+		return err
+	}
+	if err := fw.w.Flush(); err != nil {
+		return err
+	}
+	if !fw.bidirectional {
+		return nil
+	}
+	typ, _, err := readControl(fw.r)
+	if err != nil {
+		return err
+	}
+	if typ != controlFinish {
+		return fmt.Errorf("dnstap: expected FINISH control frame, got type %d", typ)
+	}
+	return nil
+}
+
+func (fw *Writer) writeControl(typ uint32, contentType string) error {
+	frameLen := 4
+	if contentType != "" {
+		frameLen += 8 + len(contentType)
+	}
+
+	b := make([]byte, 0, 8+frameLen)
+	b = binary.BigEndian.AppendUint32(b, 0) // escape
+	b = binary.BigEndian.AppendUint32(b, uint32(frameLen))
+	b = binary.BigEndian.AppendUint32(b, typ)
+	if contentType != "" {
+		b = binary.BigEndian.AppendUint32(b, controlFieldContentType)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(contentType)))
+		b = append(b, contentType...)
+	}
+
+	_, err := fw.w.Write(b)
+	return err
+}
+
+// readControl reads a control frame and returns its type and content
+// types.
+func readControl(r io.Reader) (uint32, []string, error) {
+	var hdr [8]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	if escape := binary.BigEndian.Uint32(hdr[:4]); escape != 0 {
+		return 0, nil, errors.New("dnstap: expected control frame")
+	}
+	frameLen := binary.BigEndian.Uint32(hdr[4:])
+	if frameLen < 4 || frameLen > maxControlFrameLen {
+		return 0, nil, fmt.Errorf("dnstap: invalid control frame length %d", frameLen)
+	}
+
+	frame := make([]byte, frameLen)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return 0, nil, err
+	}
+
+	typ := binary.BigEndian.Uint32(frame)
+	frame = frame[4:]
+
+	var contentTypes []string
+	for len(frame) >= 8 {
+		field := binary.BigEndian.Uint32(frame)
+		fieldLen := binary.BigEndian.Uint32(frame[4:])
+		frame = frame[8:]
+		if uint32(len(frame)) < fieldLen {
+			return 0, nil, errors.New("dnstap: truncated control field")
+		}
+		if field == controlFieldContentType {
+			contentTypes = append(contentTypes, string(frame[:fieldLen]))
+		}
+		frame = frame[fieldLen:]
+	}
+
+	return typ, contentTypes, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
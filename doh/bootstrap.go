@@ -0,0 +1,162 @@
+package doh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// minBootstrapTTL keeps very short TTLs from causing a lookup for
+	// every connection.
+	minBootstrapTTL = 30 * time.Second
+	// bootstrapTimeout bounds a background re-resolve.
+	bootstrapTimeout = 10 * time.Second
+)
+
+// WithBootstrap resolves the server's hostname by sending plain DNS
+// queries to servers (ip:port) when New is given no serverAddrs. The
+// addresses are cached for their TTL and re-resolved in the background
+// once it runs out; the old addresses stay in use until that succeeds.
+func WithBootstrap(servers ...string) Option {
+	return func(c *Client) error {
+		for _, s := range servers {
+			if _, _, err := net.SplitHostPort(s); err != nil {
+				return fmt.Errorf("invalid bootstrap server %q: %w", s, err)
+			}
+		}
+		c.bootstrapServers = servers
+		return nil
+	}
+}
+
+// bootstrap looks up the addresses of a DoH server.
+type bootstrap struct {
+	host    string
+	port    string
+	servers []string
+	client  dns.Client
+
+	mu         sync.Mutex
+	addrs      []string
+	expires    time.Time
+	refreshing bool
+}
+
+// lookup returns the server's addresses as host:port pairs.
+func (b *bootstrap) lookup(ctx context.Context) ([]string, error) {
+	b.mu.Lock()
+	addrs := b.addrs
+	if len(addrs) > 0 && time.Now().After(b.expires) && !b.refreshing {
+		b.refreshing = true
+		go b.refresh()
+	}
+	b.mu.Unlock()
+
+	if len(addrs) > 0 {
+		return addrs, nil
+	}
+	return b.resolve(ctx)
+}
+
+func (b *bootstrap) refresh() {
+	ctx, cancel := context.WithTimeout(context.Background(), bootstrapTimeout)
+	defer cancel()
+	_, err := b.resolve(ctx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refreshing = false
+	if err != nil {
+		// Keep the old addresses and try again later.
+		b.expires = time.Now().Add(minBootstrapTTL)
+	}
+}
+
+// resolve looks up the server's A and AAAA records with the first
+// bootstrap server that answers and caches them.
+func (b *bootstrap) resolve(ctx context.Context) ([]string, error) {
+	var errs []error
+	for _, server := range b.servers {
+		addrs, ttl, err := b.query(ctx, server)
+		if err != nil {
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		b.mu.Lock()
+		b.addrs = addrs
+		b.expires = time.Now().Add(ttl)
+		b.mu.Unlock()
+		return addrs, nil
+	}
+	return nil, fmt.Errorf("doh: bootstrap lookup of %s failed: %w", b.host, errors.Join(errs...))
+}
+
+func (b *bootstrap) query(ctx context.Context, server string) ([]string, time.Duration, error) {
+	type answer struct {
+		r   *dns.Msg
+		err error
+	}
+	qtypes := []uint16{dns.TypeAAAA, dns.TypeA}
+	answers := make(chan answer, len(qtypes))
+	for _, qtype := range qtypes {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(b.host), qtype)
+		go func() {
+			r, _, err := b.client.ExchangeContext(ctx, m, server)
+			answers <- answer{r, err}
+		}()
+	}
+
+	var (
+		addrs   []string
+		minTTL  uint32
+		lastErr error
+	)
+	for range qtypes {
+		a := <-answers
+		if a.err == nil && a.r.Rcode != dns.RcodeSuccess {
+			a.err = fmt.Errorf("%s: %s", server, dns.RcodeToString[a.r.Rcode])
+		}
+		if a.err != nil {
+			lastErr = a.err
+			continue
+		}
+		for _, rr := range a.r.Answer {
+			var ip net.IP
+			switch rr := rr.(type) {
+			case *dns.A:
+				ip = rr.A
+			case *dns.AAAA:
+				ip = rr.AAAA
+			default:
+				continue
+			}
+			if len(addrs) == 0 || rr.Header().Ttl < minTTL {
+				minTTL = rr.Header().Ttl
+			}
+			addrs = append(addrs, net.JoinHostPort(ip.String(), b.port))
+		}
+	}
+	if len(addrs) == 0 {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("%s: no addresses for %s", server, b.host)
+		}
+		return nil, 0, lastErr
+	}
+
+	ttl := time.Duration(minTTL) * time.Second
+	if ttl < minBootstrapTTL {
+		ttl = minBootstrapTTL
+	}
+	return addrs, ttl, nil
+}
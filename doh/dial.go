@@ -0,0 +1,125 @@
+package doh
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// connectionAttemptDelay is how long to wait on a connection attempt
+// before starting one to the next address (RFC 8305 5).
+const connectionAttemptDelay = 250 * time.Millisecond
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dialAddrs connects to whichever of addrs answers first, happy eyeballs
+// style: attempts start connectionAttemptDelay apart, or as soon as the
+// previous one fails, so a dead address only costs a short delay.
+func dialAddrs(ctx context.Context, dial dialFunc, network string, addrs []string) (net.Conn, error) {
+	connect := func(ctx context.Context, addr string) (net.Conn, error) {
+		return dial(ctx, network, addr)
+	}
+	return raceAddrs(ctx, addrs, connect, func(conn net.Conn) { conn.Close() })
+}
+
+// raceAddrs runs connect for addrs the way dialAddrs does and returns
+// the first connection made. Connections that lose the race are passed
+// to discard.
+func raceAddrs[C any](ctx context.Context, addrs []string, connect func(context.Context, string) (C, error), discard func(C)) (C, error) {
+	var zero C
+	if len(addrs) == 0 {
+		return zero, errors.New("doh: no server addresses to dial")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn C
+		err  error
+	}
+	results := make(chan result)
+
+	next := interleaveFamilies(addrs)
+	pending := 0
+	start := func() {
+		addr := next[0]
+		next = next[1:]
+		pending++
+		go func() {
+			conn, err := connect(ctx, addr)
+			select {
+			case results <- result{conn, err}:
+			case <-ctx.Done():
+				if err == nil {
+					discard(conn)
+				}
+			}
+		}()
+	}
+
+	start()
+	timer := time.NewTimer(connectionAttemptDelay)
+	defer timer.Stop()
+
+	var firstErr error
+	for {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				return r.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if len(next) > 0 {
+				start()
+				timer.Reset(connectionAttemptDelay)
+			} else if pending == 0 {
+				return zero, firstErr
+			}
+		case <-timer.C:
+			if len(next) > 0 {
+				start()
+				timer.Reset(connectionAttemptDelay)
+			}
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// interleaveFamilies reorders addrs to alternate between IPv6 and IPv4,
+// starting with the family of the first address (RFC 8305 4).
+func interleaveFamilies(addrs []string) []string {
+	var first, second []string
+	firstV4 := isIPv4(addrs[0])
+	for _, a := range addrs {
+		if isIPv4(a) == firstV4 {
+			first = append(first, a)
+		} else {
+			second = append(second, a)
+		}
+	}
+
+	out := make([]string, 0, len(addrs))
+	for len(first) > 0 || len(second) > 0 {
+		if len(first) > 0 {
+			out = append(out, first[0])
+			first = first[1:]
+		}
+		if len(second) > 0 {
+			out = append(out, second[0])
+			second = second[1:]
+		}
+	}
+	return out
+}
+
+func isIPv4(addr string) bool {
+	host, _, _ := net.SplitHostPort(addr)
+	ip := net.ParseIP(host)
+	return ip != nil && ip.To4() != nil
+}
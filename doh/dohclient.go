@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -11,24 +12,111 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/miekg/dns"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 const dohMimeType = "application/dns-message"
 
 type Client struct {
-	serverURL  string
-	httpClient *http.Client
+	serverURL string
+	host      string // host:port of serverURL
+	// addrs returns the addresses to connect to, either the fixed
+	// serverAddrs or the ones found by the bootstrap servers.
+	addrs            func(context.Context) ([]string, error)
+	serverAddrs      []string
+	bootstrapServers []string
+	bootstrap        *bootstrap // nil when serverAddrs are given
+	dial             dialFunc
+	paddingBlock     int
+	method           string
+	cache            responseCache
+	tlsConfig        *tls.Config
+	httpClient       *http.Client
+
+	// h3 is set when HTTP/3 is enabled; it is only used once the server
+	// has advertised it.
+	h3  *http.Client
+	alt altSvc
+}
+
+// Option configures a Client.
+type Option func(*Client) error
+
+// WithPadding pads queries to a multiple of blockSize bytes. A
+// blockSize of 0 disables padding. Clients pad to
+// DefaultPaddingBlockSize by default.
+func WithPadding(blockSize int) Option {
+	return func(c *Client) error {
+		if blockSize < 0 || blockSize > dns.MaxMsgSize {
+			return fmt.Errorf("invalid padding block size %d", blockSize)
+		}
+		c.paddingBlock = blockSize
+		return nil
+	}
+}
+
+// WithGET sends queries with the RFC 8484 GET method instead of POST.
+// GET requests can be cached by HTTP caches between the client and the
+// server, and their responses are cached by the Client for as long as
+// their Cache-Control max-age allows.
+func WithGET() Option {
+	return func(c *Client) error {
+		c.method = http.MethodGet
+		return nil
+	}
+}
+
+// WithServerAddrs adds addrs, in the form ip:port, to the addresses the
+// Client connects to after the serverAddr given to New. Each connection
+// goes to whichever address answers first.
+func WithServerAddrs(addrs ...string) Option {
+	return func(c *Client) error {
+		c.serverAddrs = append(c.serverAddrs, addrs...)
+		return nil
+	}
+}
+
+// WithClientCertificate presents cert to servers that ask for a client
+// certificate (mutual TLS).
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(c *Client) error {
+		c.tlsConfig.Certificates = []tls.Certificate{cert}
+		return nil
+	}
+}
+
+// WithDialer makes connections to the server with dial, for example
+// through a proxy. If New is given no serverAddr, dial is passed the
+// serverURL's host name to resolve itself. HTTP/3 isn't used with a
+// custom dialer since it can't carry QUIC.
+func WithDialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return func(c *Client) error {
+		c.dial = dial
+		return nil
+	}
+}
+
+type remoteAddrKey struct{}
+
+// WithRemoteAddrFunc returns a copy of ctx that makes Exchange call fn
+// with the address of the server the query was sent to. It isn't
+// called for answers from the Client's cache.
+func WithRemoteAddrFunc(ctx context.Context, fn func(addr string)) context.Context {
+	return context.WithValue(ctx, remoteAddrKey{}, fn)
 }
 
 // New creates a new Client pointed at serverAddr.
 // serverURL is the url of the dns server.
-// serverAddr should be in the form https://ip:port.
-// Specifying the serverName and serverAddr is required
-// to avoid needing DNS in order to perform DNS queries.
-func New(serverURL string, serverAddr string) (*Client, error) {
+// serverAddr should be in the form ip:port. WithServerAddrs adds more.
+// Specifying the serverAddr avoids needing DNS in order to perform
+// DNS queries. If it is empty the serverURL's host is looked up with
+// the servers given to WithBootstrap, or by the WithDialer dialer.
+func New(serverURL string, serverAddr string, opts ...Option) (*Client, error) {
 	u, err := url.Parse(serverURL)
 	if err != nil {
 		return nil, fmt.Errorf("parse serverURL: %w", err)
@@ -38,13 +126,52 @@ func New(serverURL string, serverAddr string) (*Client, error) {
 		return nil, errors.New("Scheme must be https")
 	}
 
-	_, _, err = net.SplitHostPort(serverAddr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid serverAddr: %w", err)
+	c := Client{
+		serverURL:    serverURL,
+		paddingBlock: DefaultPaddingBlockSize,
+		method:       http.MethodPost,
+		tlsConfig: &tls.Config{
+			ServerName: u.Hostname(),
+		},
+	}
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return nil, err
+		}
 	}
 
-	conf := tls.Config{
-		ServerName: u.Hostname(),
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	c.host = net.JoinHostPort(u.Hostname(), port)
+	serverAddrs := c.serverAddrs
+	if serverAddr != "" {
+		serverAddrs = append([]string{serverAddr}, serverAddrs...)
+	}
+	if len(serverAddrs) > 0 {
+		for _, addr := range serverAddrs {
+			if _, _, err := net.SplitHostPort(addr); err != nil {
+				return nil, fmt.Errorf("invalid serverAddr: %w", err)
+			}
+		}
+		c.addrs = func(context.Context) ([]string, error) {
+			return serverAddrs, nil
+		}
+	} else if c.dial != nil {
+		hostAddr := []string{c.host}
+		c.addrs = func(context.Context) ([]string, error) {
+			return hostAddr, nil
+		}
+	} else if len(c.bootstrapServers) > 0 {
+		c.bootstrap = &bootstrap{
+			host:    u.Hostname(),
+			port:    port,
+			servers: c.bootstrapServers,
+		}
+		c.addrs = c.bootstrap.lookup
+	} else {
+		return nil, errors.New("serverAddr or bootstrap servers are required")
 	}
 
 	dialer := &net.Dialer{
@@ -52,47 +179,93 @@ func New(serverURL string, serverAddr string) (*Client, error) {
 		KeepAlive: 30 * time.Second,
 		DualStack: true,
 	}
+	dial := c.dial
+	if dial == nil {
+		dial = dialer.DialContext
+	}
 
 	transport := http.Transport{
 		ForceAttemptHTTP2: true,
-		TLSClientConfig:   &conf,
+		TLSClientConfig:   c.tlsConfig,
 		DialContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
-			return dialer.DialContext(ctx, network, serverAddr)
+			addrs, err := c.addrs(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return dialAddrs(ctx, dial, network, addrs)
 		},
 	}
 
-	client := http.Client{
+	c.httpClient = &http.Client{
 		Transport: &transport,
 	}
-
-	c := Client{
-		serverURL:  serverURL,
-		httpClient: &client,
+	if c.alt.enabled && c.dial == nil {
+		c.h3 = &http.Client{
+			Transport: c.newH3Transport(),
+		}
 	}
 	return &c, nil
 }
 
 func (c *Client) Exchange(ctx context.Context, m *dns.Msg) (r *dns.Msg, rtt time.Duration, err error) {
-	p, err := m.Pack()
-	if err != nil {
-		return nil, 0, err
+	q := m
+	padded := c.paddingBlock > 0
+	if padded {
+		// Hide the length of the name being looked up.
+		q = Pad(m, c.paddingBlock)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, c.serverURL, bytes.NewReader(p))
-	if err != nil {
-		return nil, 0, err
+	var req *http.Request
+	if c.method == http.MethodGet {
+		// RFC 8484 4.1: use ID 0 so identical queries share a cache entry.
+		if q == m {
+			q = m.Copy()
+		}
+		q.Id = 0
+		p, err := q.Pack()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		u := c.getURL(p)
+		if body, age, ok := c.cache.get(u, time.Now()); ok {
+			r, err := unpackResponse(m, body, age, padded)
+			return r, 0, err
+		}
+
+		req, err = http.NewRequest(http.MethodGet, u, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+	} else {
+		p, err := q.Pack()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		req, err = http.NewRequest(http.MethodPost, c.serverURL, bytes.NewReader(p))
+		if err != nil {
+			return nil, 0, err
+		}
+		req.Header.Set("Content-Type", dohMimeType)
 	}
 
-	req.Header.Set("Content-Type", dohMimeType)
 	req.Header.Set("Accept", dohMimeType)
 
 	if ctx != context.Background() && ctx != context.TODO() {
 		req = req.WithContext(ctx)
 	}
 
+	// Pass the caller's trace on to the server; the propagator is a no-op
+	// unless tracing is enabled.
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
 	t := time.Now()
 
-	resp, err := c.httpClient.Do(req)
+	resp, addr, err := c.do(req)
+	if fn, ok := ctx.Value(remoteAddrKey{}).(func(string)); ok && addr != "" {
+		fn(addr)
+	}
 	if err != nil {
 		return nil, 0, err
 	}
@@ -106,21 +279,69 @@ func (c *Client) Exchange(ctx context.Context, m *dns.Msg) (r *dns.Msg, rtt time
 		return nil, 0, fmt.Errorf("dns: unexpected Content-Type %q; expected %q", ct, dohMimeType)
 	}
 
-	p, err = ioutil.ReadAll(resp.Body)
+	p, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, 0, err
 	}
 
 	rtt = time.Since(t)
 
-	r = new(dns.Msg)
-	if err := r.Unpack(p); err != nil {
+	r, err = unpackResponse(m, p, responseAge(resp.Header), padded)
+	if err != nil {
 		return r, 0, err
 	}
+	if req.Method == http.MethodGet {
+		c.cache.put(req.URL.String(), resp.Header, p, time.Now())
+	}
 
 	return r, rtt, nil
 }
 
+// Host returns the host:port of the server's url.
+func (c *Client) Host() string {
+	return c.host
+}
+
+// getURL returns the RFC 8484 GET url for the packed query p.
+func (c *Client) getURL(p []byte) string {
+	sep := "?"
+	if strings.Contains(c.serverURL, "?") {
+		sep = "&"
+	}
+	return c.serverURL + sep + "dns=" + base64.RawURLEncoding.EncodeToString(p)
+}
+
+// unpackResponse unpacks body, the answer to the caller's query m. age is how
+// long the answer spent in HTTP caches; it is taken off the record ttls
+// as RFC 8484 5.1 requires.
+func unpackResponse(m *dns.Msg, body []byte, age time.Duration, padded bool) (*dns.Msg, error) {
+	r := new(dns.Msg)
+	if err := r.Unpack(body); err != nil {
+		return r, err
+	}
+	r.Id = m.Id
+	if padded {
+		unpad(m, r)
+	}
+
+	if secs := uint32(age / time.Second); secs > 0 {
+		for _, section := range [][]dns.RR{r.Answer, r.Ns, r.Extra} {
+			for _, rr := range section {
+				hdr := rr.Header()
+				if hdr.Rrtype == dns.TypeOPT {
+					continue
+				}
+				if hdr.Ttl > secs {
+					hdr.Ttl -= secs
+				} else {
+					hdr.Ttl = 0
+				}
+			}
+		}
+	}
+	return r, nil
+}
+
 func closeHTTPBody(r io.ReadCloser) error {
 	io.Copy(ioutil.Discard, io.LimitReader(r, 8<<20))
 	return r.Close()
@@ -0,0 +1,608 @@
+package doh
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go/http3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestServer starts a TLS DoH server that answers every query with
+// handler and a Client that trusts it.
+func newTestServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, m *dns.Msg)) (*httptest.Server, *Client) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			body []byte
+			err  error
+		)
+		if r.Method == http.MethodGet {
+			body, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		} else {
+			body, err = io.ReadAll(r.Body)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m := new(dns.Msg)
+		if err := m.Unpack(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		handler(w, r, m)
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := New(srv.URL+"/dns-query", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.httpClient = srv.Client()
+	return srv, c
+}
+
+func writeReply(w http.ResponseWriter, m *dns.Msg) {
+	resp := new(dns.Msg)
+	resp.SetReply(m)
+	b, _ := resp.Pack()
+	w.Header().Set("Content-Type", dohMimeType)
+	w.Write(b)
+}
+
+func TestTracePropagation(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+
+	var traceparent string
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request, m *dns.Msg) {
+		traceparent = r.Header.Get("traceparent")
+		writeReply(w, m)
+	})
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.com.", dns.TypeA)
+	if _, _, err := c.Exchange(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := "00-0102030405060708090a0b0c0d0e0f10-0102030405060708-01"
+	if traceparent != expect {
+		t.Errorf("traceparent %q, expected %q", traceparent, expect)
+	}
+}
+
+func TestPadding(t *testing.T) {
+	var sizes []int
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request, m *dns.Msg) {
+		b, _ := m.Pack()
+		sizes = append(sizes, len(b))
+
+		resp := new(dns.Msg)
+		resp.SetReply(m)
+		resp.SetEdns0(4096, false)
+		resp.IsEdns0().Option = append(resp.IsEdns0().Option, &dns.EDNS0_PADDING{Padding: make([]byte, 100)})
+		b, _ = resp.Pack()
+		w.Header().Set("Content-Type", dohMimeType)
+		w.Write(b)
+	})
+
+	names := []string{"a.io.", "example.com.", "a-much-longer-name.for-padding.example.com."}
+	for _, name := range names {
+		for _, edns := range []bool{false, true} {
+			m := new(dns.Msg)
+			m.SetQuestion(name, dns.TypeA)
+			if edns {
+				m.SetEdns0(1232, true)
+			}
+			r, _, err := c.Exchange(context.Background(), m)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !edns && r.IsEdns0() != nil {
+				t.Errorf("%s: OPT record added for padding was returned", name)
+			}
+			if opt := r.IsEdns0(); opt != nil {
+				for _, o := range opt.Option {
+					if o.Option() == dns.EDNS0PADDING {
+						t.Errorf("%s: response padding was returned", name)
+					}
+				}
+			}
+			if m.IsEdns0() != nil && len(m.IsEdns0().Option) != 0 {
+				t.Errorf("%s: caller's message was modified", name)
+			}
+		}
+	}
+
+	for _, n := range sizes {
+		if n != DefaultPaddingBlockSize {
+			t.Errorf("query packed to %d bytes, expected %d", n, DefaultPaddingBlockSize)
+		}
+	}
+}
+
+func TestGET(t *testing.T) {
+	var (
+		requests int
+		ids      []uint16
+	)
+	_, c := newTestServer(t, func(w http.ResponseWriter, r *http.Request, m *dns.Msg) {
+		requests++
+		if r.Method != http.MethodGet {
+			t.Errorf("got %s request, expected GET", r.Method)
+		}
+		ids = append(ids, m.Id)
+
+		resp := new(dns.Msg)
+		resp.SetReply(m)
+		resp.Answer = append(resp.Answer, &dns.A{
+			Hdr: dns.RR_Header{Name: m.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+			A:   net.IPv4(192, 0, 2, 1),
+		})
+		b, _ := resp.Pack()
+		w.Header().Set("Content-Type", dohMimeType)
+		if m.Question[0].Name == "cacheable.example." {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Age", "10")
+		} else {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		w.Write(b)
+	})
+	if err := WithGET()(c); err != nil {
+		t.Fatal(err)
+	}
+
+	exchange := func(name string, id uint16) *dns.Msg {
+		t.Helper()
+		m := new(dns.Msg)
+		m.SetQuestion(name, dns.TypeA)
+		m.Id = id
+		r, _, err := c.Exchange(context.Background(), m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Id != id {
+			t.Errorf("response id %d, expected %d", r.Id, id)
+		}
+		return r
+	}
+
+	r := exchange("cacheable.example.", 1234)
+	if ttl := r.Answer[0].Header().Ttl; ttl != 290 {
+		t.Errorf("ttl %d, expected 290 after subtracting Age", ttl)
+	}
+	r = exchange("cacheable.example.", 4321)
+	if ttl := r.Answer[0].Header().Ttl; ttl > 290 {
+		t.Errorf("cached ttl %d, expected at most 290", ttl)
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests for a cacheable response, expected 1", requests)
+	}
+
+	exchange("uncacheable.example.", 1)
+	exchange("uncacheable.example.", 2)
+	if requests != 3 {
+		t.Errorf("got %d requests, expected no-store responses not to be cached", requests)
+	}
+
+	for _, id := range ids {
+		if id != 0 {
+			t.Errorf("GET query sent with id %d, expected 0", id)
+		}
+	}
+}
+
+func TestResponseCacheEviction(t *testing.T) {
+	var c responseCache
+	now := time.Now()
+	put := func(url string, maxAge int) {
+		h := http.Header{}
+		h.Set("Cache-Control", fmt.Sprintf("max-age=%d", maxAge))
+		c.put(url, h, []byte(url), now)
+	}
+	for i := 0; i < maxCachedResponses; i++ {
+		// The first entry expires soonest.
+		put(fmt.Sprintf("https://dns.example/?dns=%d", i), 60+i)
+	}
+
+	put("https://dns.example/?dns=new", 60)
+	if _, _, ok := c.get("https://dns.example/?dns=new", now); !ok {
+		t.Error("new response was not cached in a full cache")
+	}
+	if _, _, ok := c.get("https://dns.example/?dns=0", now); ok {
+		t.Error("expected the soonest expiring response to be evicted")
+	}
+	if _, _, ok := c.get("https://dns.example/?dns=1", now); !ok {
+		t.Error("expected only one response to be evicted")
+	}
+	if len(c.entries) != maxCachedResponses {
+		t.Errorf("cache has %d entries, expected %d", len(c.entries), maxCachedResponses)
+	}
+
+	// Refreshing a cached url doesn't evict anything.
+	put("https://dns.example/?dns=1", 60)
+	if _, _, ok := c.get("https://dns.example/?dns=2", now); !ok {
+		t.Error("refreshing an entry evicted another")
+	}
+}
+
+func TestHTTP3(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		protos []string
+		altSvc string
+	)
+	handler := func(w http.ResponseWriter, r *http.Request, m *dns.Msg) {
+		mu.Lock()
+		protos = append(protos, r.Proto)
+		w.Header().Set("Alt-Svc", altSvc)
+		mu.Unlock()
+		writeReply(w, m)
+	}
+	srv, _ := newTestServer(t, handler)
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h3srv := &http3.Server{
+		Handler:   srv.Config.Handler,
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: srv.TLS.Certificates}),
+	}
+	go h3srv.Serve(pc)
+	t.Cleanup(func() { h3srv.Close() })
+
+	// A UDP port nothing answers on, as if UDP were blocked.
+	dead, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dead.Close()
+
+	newClient := func(addrs ...string) *Client {
+		c, err := New(srv.URL+"/dns-query", "", WithServerAddrs(append(addrs, srv.Listener.Addr().String())...), WithHTTP3())
+		if err != nil {
+			t.Fatal(err)
+		}
+		roots := x509.NewCertPool()
+		roots.AddCert(srv.Certificate())
+		c.tlsConfig.RootCAs = roots
+		return c
+	}
+	query := func(c *Client) string {
+		t.Helper()
+		m := new(dns.Msg)
+		m.SetQuestion("example.com.", dns.TypeA)
+		var addr string
+		ctx := WithRemoteAddrFunc(context.Background(), func(a string) { addr = a })
+		if _, _, err := c.Exchange(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+		return addr
+	}
+	checkProtos := func(expect ...string) {
+		t.Helper()
+		mu.Lock()
+		defer mu.Unlock()
+		if diff := cmp.Diff(expect, protos); diff != "" {
+			t.Errorf("request protocols mismatch (-want +got):\n%s", diff)
+		}
+		protos = nil
+	}
+
+	altSvc = fmt.Sprintf(`h3=":%d"; ma=60`, pc.LocalAddr().(*net.UDPAddr).Port)
+	c := newClient()
+	query(c)
+	query(c)
+	checkProtos("HTTP/1.1", "HTTP/3.0")
+
+	// Nothing listens on 127.0.0.2, so both transports have to move on
+	// to the second address.
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	c = newClient("127.0.0.2:" + port)
+	query(c)
+	if addr, expect := query(c), pc.LocalAddr().String(); addr != expect {
+		t.Errorf("HTTP/3 query went to %q, expected %q", addr, expect)
+	}
+	checkProtos("HTTP/1.1", "HTTP/3.0")
+
+	altSvc = fmt.Sprintf(`h3=":%d"`, dead.LocalAddr().(*net.UDPAddr).Port)
+	c = newClient()
+	query(c)
+	query(c)
+	query(c)
+	checkProtos("HTTP/1.1", "HTTP/1.1", "HTTP/1.1")
+}
+
+func TestSPKIPins(t *testing.T) {
+	srv, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, m *dns.Msg) {
+		writeReply(w, m)
+	})
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	sum := sha256.Sum256(srv.Certificate().RawSubjectPublicKeyInfo)
+	pin := base64.StdEncoding.EncodeToString(sum[:])
+	other := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	exchange := func(opts ...Option) error {
+		c, err := New(srv.URL+"/dns-query", srv.Listener.Addr().String(), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := new(dns.Msg)
+		m.SetQuestion("example.com.", dns.TypeA)
+		_, _, err = c.Exchange(context.Background(), m)
+		return err
+	}
+
+	if err := exchange(WithRootCAs(roots), WithSPKIPins(other, pin)); err != nil {
+		t.Errorf("pinned key rejected: %s", err)
+	}
+
+	err := exchange(WithRootCAs(roots), WithSPKIPins(other))
+	var pinErr *PinError
+	if !errors.As(err, &pinErr) {
+		t.Fatalf("expected a PinError, got %v", err)
+	}
+	if diff := cmp.Diff([]string{pin}, pinErr.Keys); diff != "" {
+		t.Errorf("presented keys mismatch (-want +got):\n%s", diff)
+	}
+
+	// Without the CA the chain doesn't verify and pins aren't consulted.
+	err = exchange(WithSPKIPins(pin))
+	if err == nil || errors.As(err, &pinErr) {
+		t.Errorf("expected a certificate verification error, got %v", err)
+	}
+
+	if _, err := New(srv.URL, srv.Listener.Addr().String(), WithSPKIPins("bm90IGEgcGlu")); err == nil {
+		t.Error("expected an error for a malformed pin")
+	}
+}
+
+func TestClientCertificate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "dnsforward"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(leaf)
+
+	var subject string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject = r.TLS.PeerCertificates[0].Subject.CommonName
+		body, _ := io.ReadAll(r.Body)
+		m := new(dns.Msg)
+		if err := m.Unpack(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeReply(w, m)
+	}))
+	srv.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	exchange := func(opts ...Option) error {
+		c, err := New(srv.URL+"/dns-query", srv.Listener.Addr().String(), append(opts, WithRootCAs(roots))...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := new(dns.Msg)
+		m.SetQuestion("example.com.", dns.TypeA)
+		_, _, err = c.Exchange(context.Background(), m)
+		return err
+	}
+
+	if err := exchange(); err == nil {
+		t.Error("expected the server to reject a client without a certificate")
+	}
+
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	if err := exchange(WithClientCertificate(cert)); err != nil {
+		t.Fatal(err)
+	}
+	if subject != "dnsforward" {
+		t.Errorf("server saw client certificate %q, expected %q", subject, "dnsforward")
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	srv, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, m *dns.Msg) {
+		writeReply(w, m)
+	})
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	var (
+		mu      sync.Mutex
+		lookups int
+	)
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dnsSrv := &dns.Server{
+		PacketConn: pc,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, m *dns.Msg) {
+			resp := new(dns.Msg)
+			resp.SetReply(m)
+			if m.Question[0].Name == "example.com." && m.Question[0].Qtype == dns.TypeA {
+				mu.Lock()
+				lookups++
+				mu.Unlock()
+				// Nothing listens on 127.0.0.2, so the client has to fail
+				// over to the second address.
+				resp.Answer = []dns.RR{
+					&dns.A{Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60}, A: net.ParseIP("127.0.0.2")},
+					&dns.A{Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60}, A: net.ParseIP("127.0.0.1")},
+				}
+			}
+			w.WriteMsg(resp)
+		}),
+	}
+	go dnsSrv.ActivateAndServe()
+	t.Cleanup(func() { dnsSrv.Shutdown() })
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	// A dead bootstrap server first, to check the next one is used.
+	c, err := New("https://example.com:"+port+"/dns-query", "", WithBootstrap("127.0.0.1:1", pc.LocalAddr().String()), WithRootCAs(roots))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := func() {
+		t.Helper()
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		var addr string
+		ctx := WithRemoteAddrFunc(context.Background(), func(a string) { addr = a })
+		if _, _, err := c.Exchange(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+		if expect := "127.0.0.1:" + port; addr != expect {
+			t.Errorf("query went to %q, expected %q", addr, expect)
+		}
+	}
+	query()
+
+	addrs, err := c.addrs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{"127.0.0.2:" + port, "127.0.0.1:" + port}
+	if diff := cmp.Diff(expect, addrs); diff != "" {
+		t.Errorf("addresses mismatch (-want +got):\n%s", diff)
+	}
+
+	// Once the addresses expire they are still used while a new lookup
+	// happens in the background.
+	c.httpClient.CloseIdleConnections()
+	bs := c.bootstrap
+	bs.mu.Lock()
+	bs.expires = time.Now().Add(-time.Second)
+	bs.mu.Unlock()
+	query()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := lookups
+		mu.Unlock()
+		if n == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d lookups, expected 2", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := New("https://example.com/dns-query", ""); err == nil {
+		t.Error("expected an error without serverAddr or bootstrap servers")
+	}
+}
+
+func TestDialAddrs(t *testing.T) {
+	expectOrder := []string{"[2001:db8::1]:443", "192.0.2.1:443", "[2001:db8::2]:443", "192.0.2.2:443", "192.0.2.3:443"}
+	got := interleaveFamilies([]string{"[2001:db8::1]:443", "[2001:db8::2]:443", "192.0.2.1:443", "192.0.2.2:443", "192.0.2.3:443"})
+	if diff := cmp.Diff(expectOrder, got); diff != "" {
+		t.Errorf("address order mismatch (-want +got):\n%s", diff)
+	}
+
+	var (
+		mu     sync.Mutex
+		dialed []string
+	)
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		mu.Lock()
+		dialed = append(dialed, addr)
+		mu.Unlock()
+		switch addr {
+		case "refused:443":
+			return nil, errors.New("connection refused")
+		case "blackhole:443":
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		c1, c2 := net.Pipe()
+		c2.Close()
+		return c1, nil
+	}
+
+	// A refused address is skipped right away and a silent one after
+	// connectionAttemptDelay.
+	start := time.Now()
+	conn, err := dialAddrs(context.Background(), dial, "tcp", []string{"refused:443", "blackhole:443", "ok:443"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if d := time.Since(start); d > 2*connectionAttemptDelay {
+		t.Errorf("connecting took %s", d)
+	}
+	mu.Lock()
+	if diff := cmp.Diff([]string{"refused:443", "blackhole:443", "ok:443"}, dialed); diff != "" {
+		t.Errorf("dial order mismatch (-want +got):\n%s", diff)
+	}
+	mu.Unlock()
+
+	if _, err := dialAddrs(context.Background(), dial, "tcp", []string{"refused:443", "refused:443"}); err == nil {
+		t.Error("expected an error when every address fails")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := dialAddrs(ctx, dial, "tcp", []string{"blackhole:443"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package doh
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+const (
+	// h3HandshakeTimeout bounds how long a request waits on a QUIC
+	// handshake before falling back to HTTP/2, for networks that drop
+	// UDP.
+	h3HandshakeTimeout = 2 * time.Second
+	// h3BrokenInterval is how long HTTP/3 is avoided after it failed.
+	h3BrokenInterval = 5 * time.Minute
+	// defaultAltSvcMaxAge is the Alt-Svc lifetime when ma is not given
+	// (RFC 7838 3.1).
+	defaultAltSvcMaxAge = 24 * time.Hour
+)
+
+// WithHTTP3 lets the Client send queries over HTTP/3 once the server
+// advertises it with an Alt-Svc header. If an HTTP/3 request fails, for
+// example because UDP is blocked, it is retried over HTTP/2 and HTTP/3
+// is not tried again for a few minutes.
+func WithHTTP3() Option {
+	return func(c *Client) error {
+		c.alt.enabled = true
+		return nil
+	}
+}
+
+func (c *Client) newH3Transport() *http3.Transport {
+	return &http3.Transport{
+		TLSClientConfig: c.tlsConfig,
+		QUICConfig: &quic.Config{
+			HandshakeIdleTimeout: h3HandshakeTimeout,
+		},
+		Dial: func(ctx context.Context, _ string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+			addrs, err := c.addrs(ctx)
+			if err != nil {
+				return nil, err
+			}
+			// The alternative is on the same hosts as the server, so
+			// no further DNS lookup is needed.
+			port := c.alt.port(time.Now())
+			h3Addrs := make([]string, 0, len(addrs))
+			for _, addr := range addrs {
+				host, _, err := net.SplitHostPort(addr)
+				if err != nil {
+					return nil, err
+				}
+				h3Addrs = append(h3Addrs, net.JoinHostPort(host, port))
+			}
+
+			connect := func(ctx context.Context, addr string) (quic.EarlyConnection, error) {
+				return quic.DialAddrEarly(ctx, addr, tlsCfg, cfg)
+			}
+			discard := func(conn quic.EarlyConnection) {
+				conn.CloseWithError(0, "")
+			}
+			conn, err := raceAddrs(ctx, h3Addrs, connect, discard)
+			if err != nil {
+				return nil, err
+			}
+			c.alt.setRemoteAddr(conn.RemoteAddr().String())
+			return conn, nil
+		},
+	}
+}
+
+// do sends req over HTTP/3 when the server supports it and otherwise
+// over the regular transport. It returns the address req was sent to.
+func (c *Client) do(req *http.Request) (*http.Response, string, error) {
+	if c.h3 == nil {
+		return c.doTCP(req)
+	}
+
+	if c.alt.port(time.Now()) != "" {
+		resp, err := c.h3.Do(req)
+		if err == nil {
+			c.alt.update(resp.Header, time.Now())
+			return resp, c.alt.remoteAddr(), nil
+		}
+		if req.Context().Err() != nil {
+			return nil, "", err
+		}
+		c.alt.markBroken(time.Now())
+
+		req = req.Clone(req.Context())
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, "", err
+			}
+			req.Body = body
+		}
+	}
+
+	resp, addr, err := c.doTCP(req)
+	if err == nil {
+		c.alt.update(resp.Header, time.Now())
+	}
+	return resp, addr, err
+}
+
+// doTCP sends req over the regular transport and returns the address of
+// the connection it was sent on.
+func (c *Client) doTCP(req *http.Request) (*http.Response, string, error) {
+	var (
+		mu   sync.Mutex
+		addr string
+	)
+	// GotConn may run on the transport's goroutine after Do gave up on
+	// a canceled request.
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			addr = info.Conn.RemoteAddr().String()
+		},
+	}
+	resp, err := c.httpClient.Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+
+	mu.Lock()
+	defer mu.Unlock()
+	return resp, addr, err
+}
+
+// altSvc tracks whether and where the server offers HTTP/3.
+type altSvc struct {
+	enabled bool
+
+	mu          sync.Mutex
+	h3Port      string
+	expires     time.Time
+	brokenUntil time.Time
+	h3Addr      string // address of the last QUIC connection
+}
+
+func (a *altSvc) remoteAddr() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.h3Addr
+}
+
+func (a *altSvc) setRemoteAddr(addr string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.h3Addr = addr
+}
+
+// port returns the port to use for HTTP/3, or "" if it isn't
+// available.
+func (a *altSvc) port(now time.Time) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.h3Port == "" || now.After(a.expires) || now.Before(a.brokenUntil) {
+		return ""
+	}
+	return a.h3Port
+}
+
+func (a *altSvc) markBroken(now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.brokenUntil = now.Add(h3BrokenInterval)
+}
+
+// update records the HTTP/3 alternative advertised in h, if any.
+func (a *altSvc) update(h http.Header, now time.Time) {
+	v := h.Get("Alt-Svc")
+	if v == "" {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if strings.TrimSpace(v) == "clear" {
+		a.h3Port = ""
+		return
+	}
+
+	port, maxAge, ok := parseAltSvcH3(v)
+	if !ok {
+		return
+	}
+	a.h3Port = port
+	a.expires = now.Add(maxAge)
+}
+
+// parseAltSvcH3 returns the port and lifetime of the h3 alternative in
+// an Alt-Svc header value.
+func parseAltSvcH3(v string) (port string, maxAge time.Duration, ok bool) {
+	for _, entry := range strings.Split(v, ",") {
+		params := strings.Split(entry, ";")
+		proto, authority, found := strings.Cut(strings.TrimSpace(params[0]), "=")
+		if !found || proto != "h3" {
+			continue
+		}
+		_, port, err := net.SplitHostPort(strings.Trim(authority, `"`))
+		if err != nil || port == "" {
+			continue
+		}
+
+		maxAge = defaultAltSvcMaxAge
+		for _, p := range params[1:] {
+			name, value, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if name == "ma" {
+				if n, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+					maxAge = time.Duration(n) * time.Second
+				}
+			}
+		}
+		return port, maxAge, true
+	}
+	return "", 0, false
+}
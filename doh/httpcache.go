@@ -0,0 +1,121 @@
+package doh
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxCachedResponses = 1024
+
+// responseCache holds GET responses for as long as their Cache-Control
+// max-age allows.
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedResponse
+}
+
+type cachedResponse struct {
+	body    []byte
+	age     time.Duration // Age of the response when it was stored
+	stored  time.Time
+	expires time.Time
+}
+
+// get returns the body cached for url and its current age.
+func (c *responseCache) get(url string, now time.Time) ([]byte, time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e := c.entries[url]
+	if e == nil {
+		return nil, 0, false
+	}
+	if !now.Before(e.expires) {
+		delete(c.entries, url)
+		return nil, 0, false
+	}
+	return e.body, e.age + now.Sub(e.stored), true
+}
+
+// put caches body for url if the response headers allow it.
+func (c *responseCache) put(url string, h http.Header, body []byte, now time.Time) {
+	maxAge, ok := cacheMaxAge(h)
+	if !ok {
+		return
+	}
+	age := responseAge(h)
+	if age >= maxAge {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*cachedResponse)
+	}
+	if _, ok := c.entries[url]; !ok && len(c.entries) >= maxCachedResponses {
+		c.evict(now)
+	}
+	c.entries[url] = &cachedResponse{
+		body:    body,
+		age:     age,
+		stored:  now,
+		expires: now.Add(maxAge - age),
+	}
+}
+
+// evict makes room for a new entry by removing the expired entries, or
+// if there are none the one closest to expiring.
+func (c *responseCache) evict(now time.Time) {
+	var (
+		soonest    string
+		soonestExp time.Time
+	)
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+			continue
+		}
+		if soonest == "" || e.expires.Before(soonestExp) {
+			soonest, soonestExp = k, e.expires
+		}
+	}
+	if len(c.entries) >= maxCachedResponses {
+		delete(c.entries, soonest)
+	}
+}
+
+// cacheMaxAge returns the freshness lifetime from the Cache-Control
+// header, if the response may be cached at all.
+func cacheMaxAge(h http.Header) (time.Duration, bool) {
+	var (
+		maxAge time.Duration
+		found  bool
+	)
+	for _, directive := range strings.Split(h.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache":
+			return 0, false
+		case "max-age":
+			n, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err != nil || n <= 0 {
+				return 0, false
+			}
+			maxAge, found = time.Duration(n)*time.Second, true
+		}
+	}
+	return maxAge, found
+}
+
+// responseAge returns the Age header, the time a response already spent
+// in HTTP caches.
+func responseAge(h http.Header) time.Duration {
+	n, err := strconv.Atoi(h.Get("Age"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second
+}
@@ -0,0 +1,59 @@
+package doh
+
+import "github.com/miekg/dns"
+
+// DefaultPaddingBlockSize is the query block length recommended by
+// RFC 8467.
+const DefaultPaddingBlockSize = 128
+
+// paddingOptionLen is the size of an EDNS0 padding option with no
+// padding bytes.
+const paddingOptionLen = 4
+
+// Pad returns a copy of m with an RFC 7830 padding option sized so the
+// packed message is a multiple of blockSize bytes, following the block
+// length strategy of RFC 8467. An OPT record is added if m has none.
+func Pad(m *dns.Msg, blockSize int) *dns.Msg {
+	m = m.Copy()
+	opt := m.IsEdns0()
+	if opt == nil {
+		m.SetEdns0(dns.DefaultMsgSize, false)
+		opt = m.IsEdns0()
+	}
+	options := opt.Option[:0]
+	for _, o := range opt.Option {
+		if o.Option() != dns.EDNS0PADDING {
+			options = append(options, o)
+		}
+	}
+	opt.Option = options
+
+	n := m.Len() + paddingOptionLen
+	padLen := (blockSize - n%blockSize) % blockSize
+	opt.Option = append(opt.Option, &dns.EDNS0_PADDING{Padding: make([]byte, padLen)})
+	return m
+}
+
+// unpad removes what Pad added from resp, the answer to the unpadded
+// query q.
+func unpad(q, resp *dns.Msg) {
+	if q.IsEdns0() == nil {
+		extra := resp.Extra[:0]
+		for _, rr := range resp.Extra {
+			if rr.Header().Rrtype != dns.TypeOPT {
+				extra = append(extra, rr)
+			}
+		}
+		resp.Extra = extra
+		return
+	}
+	if opt := resp.IsEdns0(); opt != nil {
+		options := opt.Option[:0]
+		for _, o := range opt.Option {
+			if o.Option() != dns.EDNS0PADDING {
+				options = append(options, o)
+			}
+		}
+		opt.Option = options
+	}
+}
@@ -0,0 +1,68 @@
+package doh
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// PinError is returned when none of the keys in a server's certificate
+// chain match the pins set with WithSPKIPins.
+type PinError struct {
+	ServerName string
+	// Keys are the pins of the keys the server presented.
+	Keys []string
+}
+
+func (e *PinError) Error() string {
+	return fmt.Sprintf("doh: certificate chain for %s matches no pinned key (got %s)", e.ServerName, strings.Join(e.Keys, ", "))
+}
+
+// WithSPKIPins only accepts servers with one of the given public keys in
+// their verified certificate chain. A pin is the base64 encoded SHA-256
+// hash of a DER encoded SubjectPublicKeyInfo, as used by HPKP.
+func WithSPKIPins(pins ...string) Option {
+	return func(c *Client) error {
+		set := make(map[[sha256.Size]byte]bool)
+		for _, p := range pins {
+			b, err := base64.StdEncoding.DecodeString(p)
+			if err != nil || len(b) != sha256.Size {
+				return fmt.Errorf("invalid SPKI pin %q", p)
+			}
+			set[[sha256.Size]byte(b)] = true
+		}
+		if len(set) == 0 {
+			return nil
+		}
+
+		c.tlsConfig.VerifyPeerCertificate = func(_ [][]byte, chains [][]*x509.Certificate) error {
+			var keys []string
+			seen := make(map[[sha256.Size]byte]bool)
+			for _, chain := range chains {
+				for _, cert := range chain {
+					sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+					if set[sum] {
+						return nil
+					}
+					if !seen[sum] {
+						seen[sum] = true
+						keys = append(keys, base64.StdEncoding.EncodeToString(sum[:]))
+					}
+				}
+			}
+			return &PinError{ServerName: c.tlsConfig.ServerName, Keys: keys}
+		}
+		return nil
+	}
+}
+
+// WithRootCAs verifies the server's certificate against roots instead of
+// the system certificate pool.
+func WithRootCAs(roots *x509.CertPool) Option {
+	return func(c *Client) error {
+		c.tlsConfig.RootCAs = roots
+		return nil
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+)
+
+const (
+	defaultECSIPv4Prefix = 24
+	defaultECSIPv6Prefix = 56
+)
+
+var cgnatNet = &net.IPNet{IP: net.IPv4(100, 64, 0, 0).To4(), Mask: net.CIDRMask(10, 32)}
+
+// ecsPolicy decides what EDNS Client Subnet option a backend sees.
+type ecsPolicy struct {
+	mode       conf.Ecs_Mode
+	ipv4Prefix uint8
+	ipv6Prefix uint8
+	address    net.IP
+}
+
+// stripECS is the policy for backends with nothing configured.
+var stripECS = &ecsPolicy{mode: conf.Ecs_STRIP}
+
+func newECSPolicy(c *conf.Ecs) (*ecsPolicy, error) {
+	if c == nil {
+		return stripECS, nil
+	}
+
+	p := &ecsPolicy{
+		mode:       c.Mode,
+		ipv4Prefix: defaultECSIPv4Prefix,
+		ipv6Prefix: defaultECSIPv6Prefix,
+	}
+	if c.Ipv4Prefix > 0 {
+		if c.Ipv4Prefix > 32 {
+			return nil, fmt.Errorf("ipv4_prefix %d is longer than 32", c.Ipv4Prefix)
+		}
+		p.ipv4Prefix = uint8(c.Ipv4Prefix)
+	}
+	if c.Ipv6Prefix > 0 {
+		if c.Ipv6Prefix > 128 {
+			return nil, fmt.Errorf("ipv6_prefix %d is longer than 128", c.Ipv6Prefix)
+		}
+		p.ipv6Prefix = uint8(c.Ipv6Prefix)
+	}
+	if c.Address != "" {
+		p.address = net.ParseIP(c.Address)
+		if p.address == nil {
+			return nil, fmt.Errorf("invalid address %q", c.Address)
+		}
+	}
+	return p, nil
+}
+
+// query returns m as it should be sent upstream for a client at
+// clientIP, which may be nil. m is copied before it is changed.
+func (p *ecsPolicy) query(m *dns.Msg, clientIP net.IP) *dns.Msg {
+	if p.mode == conf.Ecs_PASS {
+		return m
+	}
+
+	existing := clientSubnet(m)
+
+	var subnet *dns.EDNS0_SUBNET
+	if p.mode == conf.Ecs_ADD {
+		if existing != nil && existing.SourceNetmask == 0 {
+			// RFC 7871 7.1.2: the client asked for its address not to be
+			// used.
+			return m
+		}
+		subnet = p.subnet(clientIP)
+	}
+	if existing == nil && subnet == nil {
+		return m
+	}
+
+	out := m.Copy()
+	opt := out.IsEdns0()
+	if opt == nil {
+		out.SetEdns0(dns.DefaultMsgSize, false)
+		opt = out.IsEdns0()
+	}
+	options := opt.Option[:0]
+	for _, o := range opt.Option {
+		if o.Option() != dns.EDNS0SUBNET {
+			options = append(options, o)
+		}
+	}
+	if subnet != nil {
+		options = append(options, subnet)
+	}
+	opt.Option = options
+	return out
+}
+
+// subnet returns the option to add for a client at clientIP, or nil if
+// there is no public address to send.
+func (p *ecsPolicy) subnet(clientIP net.IP) *dns.EDNS0_SUBNET {
+	ip := p.address
+	if ip == nil {
+		ip = clientIP
+		if ip == nil || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() || cgnatNet.Contains(ip) {
+			return nil
+		}
+	}
+
+	s := &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET}
+	if ip4 := ip.To4(); ip4 != nil {
+		s.Family = 1
+		s.SourceNetmask = p.ipv4Prefix
+		s.Address = ip4.Mask(net.CIDRMask(int(p.ipv4Prefix), 32))
+	} else {
+		s.Family = 2
+		s.SourceNetmask = p.ipv6Prefix
+		s.Address = ip.Mask(net.CIDRMask(int(p.ipv6Prefix), 128))
+	}
+	return s
+}
+
+// response removes what query added from resp, the upstream answer to
+// the client's query r: the client subnet echoed back by the backend
+// and, if r had none, the OPT record.
+func (p *ecsPolicy) response(r, resp *dns.Msg) {
+	if p.mode == conf.Ecs_PASS || resp == nil {
+		return
+	}
+	if r.IsEdns0() == nil {
+		extra := resp.Extra[:0]
+		for _, rr := range resp.Extra {
+			if rr.Header().Rrtype != dns.TypeOPT {
+				extra = append(extra, rr)
+			}
+		}
+		resp.Extra = extra
+		return
+	}
+	if opt := resp.IsEdns0(); opt != nil {
+		options := opt.Option[:0]
+		for _, o := range opt.Option {
+			if o.Option() != dns.EDNS0SUBNET {
+				options = append(options, o)
+			}
+		}
+		opt.Option = options
+	}
+}
+
+func clientSubnet(m *dns.Msg) *dns.EDNS0_SUBNET {
+	opt := m.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+	for _, o := range opt.Option {
+		if s, ok := o.(*dns.EDNS0_SUBNET); ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// ecsScope returns the client subnets the ADD mode backends would be
+// sent for r from the client in ctx. Their answers can differ per
+// subnet, so the scope is part of the cache and coalescing keys.
+func (s *server) ecsScope(ctx context.Context, r *dns.Msg) string {
+	if existing := clientSubnet(r); existing != nil && existing.SourceNetmask == 0 {
+		// Sent upstream as is, and already part of the keys.
+		return ""
+	}
+	ip := clientIP(ctx)
+	var scope []string
+	for _, c := range s.clients {
+		if c.ecs == nil || c.ecs.mode != conf.Ecs_ADD {
+			continue
+		}
+		subnet := c.ecs.subnet(ip)
+		if subnet == nil {
+			continue
+		}
+		k := fmt.Sprintf("%s/%d", subnet.Address, subnet.SourceNetmask)
+		if !slices.Contains(scope, k) {
+			scope = append(scope, k)
+		}
+	}
+	return strings.Join(scope, ",")
+}
+
+type clientAddrKey struct{}
+
+// withClientIP records the address of the client that sent the query
+// being resolved with ctx.
+func withClientIP(ctx context.Context, ip net.IP) context.Context {
+	return context.WithValue(ctx, clientAddrKey{}, ip)
+}
+
+func clientIP(ctx context.Context) net.IP {
+	ip, _ := ctx.Value(clientAddrKey{}).(net.IP)
+	return ip
+}
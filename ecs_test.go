@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+)
+
+func TestECSPolicy(t *testing.T) {
+	var sent *dns.EDNS0_SUBNET
+	backend := exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		sent = clientSubnet(m)
+		resp := answerA(m, "192.0.2.1")
+		if sent != nil {
+			resp.SetEdns0(4096, false)
+			echo := *sent
+			echo.SourceScope = sent.SourceNetmask
+			resp.IsEdns0().Option = append(resp.IsEdns0().Option, &echo)
+		}
+		return resp, 0, nil
+	})
+
+	publicV4 := &net.UDPAddr{IP: net.ParseIP("203.0.113.77"), Port: 5353}
+	publicV6 := &net.UDPAddr{IP: net.ParseIP("2001:db8:aaaa:bbcc::1"), Port: 5353}
+	private := &net.UDPAddr{IP: net.ParseIP("192.168.1.20"), Port: 5353}
+
+	clientECS := &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		Family:        1,
+		SourceNetmask: 32,
+		Address:       net.ParseIP("198.51.100.9").To4(),
+	}
+
+	checks := []struct {
+		desc      string
+		policy    *conf.Ecs
+		client    net.Addr
+		clientECS bool
+		expect    string // sent subnet as addr/prefix, empty for none
+	}{
+		{desc: "default strips", client: publicV4, clientECS: true},
+		{desc: "add v4", policy: &conf.Ecs{Mode: conf.Ecs_ADD}, client: publicV4, expect: "203.0.113.0/24"},
+		{desc: "add v6", policy: &conf.Ecs{Mode: conf.Ecs_ADD}, client: publicV6, expect: "2001:db8:aaaa:bb00::/56"},
+		{desc: "add replaces client's", policy: &conf.Ecs{Mode: conf.Ecs_ADD, Ipv4Prefix: 20}, client: publicV4, clientECS: true, expect: "203.0.112.0/20"},
+		{desc: "add skips private", policy: &conf.Ecs{Mode: conf.Ecs_ADD}, client: private},
+		{desc: "add fixed address", policy: &conf.Ecs{Mode: conf.Ecs_ADD, Address: "198.51.100.200"}, client: private, expect: "198.51.100.0/24"},
+		{desc: "pass", policy: &conf.Ecs{Mode: conf.Ecs_PASS}, client: private, clientECS: true, expect: "198.51.100.9/32"},
+	}
+
+	for _, check := range checks {
+		policy, err := newECSPolicy(check.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := newTestServer(conf.Config_InOrder, backend)
+		s.clients[0].ecs = policy
+
+		req := new(dns.Msg)
+		req.SetQuestion("example.com.", dns.TypeA)
+		if check.clientECS {
+			req.SetEdns0(1232, false)
+			req.IsEdns0().Option = append(req.IsEdns0().Option, clientECS)
+		}
+
+		sent = nil
+		w := &recorder{remote: check.client}
+		s.handleRequest(w, req)
+
+		var got string
+		if sent != nil {
+			got = (&net.IPNet{IP: sent.Address, Mask: net.CIDRMask(int(sent.SourceNetmask), len(sent.Address)*8)}).String()
+		}
+		if got != check.expect {
+			t.Errorf("%s: sent subnet %q, expected %q", check.desc, got, check.expect)
+		}
+
+		if !check.clientECS {
+			if w.msg.IsEdns0() != nil {
+				t.Errorf("%s: response has an OPT record the client didn't ask for", check.desc)
+			}
+		} else if echoed := clientSubnet(w.msg) != nil; echoed != (check.policy != nil && check.policy.Mode == conf.Ecs_PASS) {
+			t.Errorf("%s: client subnet echoed in response: %t", check.desc, echoed)
+		}
+	}
+}
+
+func TestECSScope(t *testing.T) {
+	release := make(chan struct{})
+	var calls int32
+	backend := exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		// Answer with the subnet's network address so each subnet gets
+		// a different answer.
+		return answerA(m, clientSubnet(m).Address.String()), 0, nil
+	})
+	s := newTestServer(conf.Config_InOrder, backend)
+	s.clients[0].ecs = &ecsPolicy{mode: conf.Ecs_ADD, ipv4Prefix: 24, ipv6Prefix: 56}
+	s.cache = newCache(&conf.Cache{})
+
+	query := func(client string) *recorder {
+		req := new(dns.Msg)
+		req.SetQuestion("example.com.", dns.TypeA)
+		w := &recorder{remote: &net.UDPAddr{IP: net.ParseIP(client), Port: 5353}}
+		s.handleRequest(w, req)
+		return w
+	}
+
+	// Two clients on different subnets at the same time must neither
+	// coalesce nor share a cache entry.
+	var wg sync.WaitGroup
+	writers := make([]*recorder, 2)
+	for i, client := range []string{"203.0.113.77", "198.51.100.5"} {
+		wg.Add(1)
+		go func(i int, client string) {
+			defer wg.Done()
+			writers[i] = query(client)
+		}(i, client)
+	}
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline) && atomic.LoadInt32(&calls) < 2; {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	for i, expect := range []string{"203.0.113.0", "198.51.100.0"} {
+		if got := writers[i].msg.Answer[0].(*dns.A).A.String(); got != expect {
+			t.Errorf("client %d got %s, expected %s", i, got, expect)
+		}
+	}
+
+	// A client in the first subnet is answered from the cache.
+	w := query("203.0.113.99")
+	if got := w.msg.Answer[0].(*dns.A).A.String(); got != "203.0.113.0" {
+		t.Errorf("got %s, expected 203.0.113.0", got)
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("got %d upstream exchanges, expected 2", got)
+	}
+}
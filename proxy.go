@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+var proxyDialer = &net.Dialer{
+	Timeout:   30 * time.Second,
+	KeepAlive: 30 * time.Second,
+}
+
+// newProxyDialer returns a dial function that connects through the proxy
+// at rawURL: socks5://host:port, socks5h://host:port or http://host:port
+// (HTTP CONNECT), with optional user:password@ credentials. Without
+// credentials a SOCKS5 proxy is sent isolationKey as the username, so
+// Tor keeps each upstream on its own circuit (IsolateSOCKSAuth).
+//
+// As with curl, only socks5h and http proxies are handed host names to
+// resolve. A socks5 dialer refuses them rather than look them up
+// locally.
+func newProxyDialer(rawURL, isolationKey string) (dialFunc, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy %q has no host", rawURL)
+	}
+
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		auth := &proxy.Auth{User: isolationKey, Password: "dnsforward"}
+		if u.User != nil {
+			auth.User = u.User.Username()
+			auth.Password, _ = u.User.Password()
+		}
+		d, err := proxy.SOCKS5("tcp", u.Host, auth, proxyDialer)
+		if err != nil {
+			return nil, err
+		}
+		dial := d.(proxy.ContextDialer).DialContext
+		if u.Scheme == "socks5h" {
+			return dial, nil
+		}
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if host, _, err := net.SplitHostPort(addr); err == nil && net.ParseIP(host) == nil {
+				return nil, fmt.Errorf("socks5 proxy can't resolve %s, use socks5h:// to have the proxy look it up", host)
+			}
+			return dial(ctx, network, addr)
+		}, nil
+	case "http":
+		var authz string
+		if u.User != nil {
+			password, _ := u.User.Password()
+			authz = "Basic " + base64.StdEncoding.EncodeToString([]byte(u.User.Username()+":"+password))
+		}
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialConnect(ctx, u.Host, authz, addr)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+}
+
+// dialConnect opens a tunnel to addr through the HTTP proxy at
+// proxyAddr.
+func dialConnect(ctx context.Context, proxyAddr, authz, addr string) (net.Conn, error) {
+	conn, err := proxyDialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if authz != "" {
+		req.Header.Set("Proxy-Authorization", authz)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// DNS and TLS clients speak first, so the proxy sends nothing after
+	// its response that the bufio.Reader could swallow.
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT %s: %s", addr, resp.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/x509"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/doh"
+)
+
+// socksServer is a minimal SOCKS5 proxy that records the usernames it is
+// sent and connects names in hosts to the given addresses instead of
+// resolving them.
+type socksServer struct {
+	ln    net.Listener
+	hosts map[string]string
+
+	mu    sync.Mutex
+	users []string
+}
+
+func newSOCKSServer(t *testing.T, hosts map[string]string) *socksServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &socksServer{ln: ln, hosts: hosts}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *socksServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	// Greeting: pick username/password if offered.
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return
+	}
+	methods := make([]byte, hdr[1])
+	io.ReadFull(r, methods)
+	method := byte(0x00)
+	for _, m := range methods {
+		if m == 0x02 {
+			method = 0x02
+		}
+	}
+	conn.Write([]byte{0x05, method})
+
+	if method == 0x02 {
+		ver, _ := r.ReadByte()
+		if ver != 0x01 {
+			return
+		}
+		n, _ := r.ReadByte()
+		user := make([]byte, n)
+		io.ReadFull(r, user)
+		n, _ = r.ReadByte()
+		io.ReadFull(r, make([]byte, n))
+		s.mu.Lock()
+		s.users = append(s.users, string(user))
+		s.mu.Unlock()
+		conn.Write([]byte{0x01, 0x00})
+	}
+
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(r, req); err != nil || req[1] != 0x01 {
+		return
+	}
+	var host string
+	switch req[3] {
+	case 0x01:
+		ip := make([]byte, 4)
+		io.ReadFull(r, ip)
+		host = net.IP(ip).String()
+	case 0x03:
+		n, _ := r.ReadByte()
+		name := make([]byte, n)
+		io.ReadFull(r, name)
+		host = string(name)
+	case 0x04:
+		ip := make([]byte, 16)
+		io.ReadFull(r, ip)
+		host = net.IP(ip).String()
+	}
+	port := make([]byte, 2)
+	io.ReadFull(r, port)
+	addr := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port))))
+	if mapped, ok := s.hosts[addr]; ok {
+		addr = mapped
+	}
+
+	upstream, err := net.Dial("tcp", addr)
+	if err != nil {
+		conn.Write([]byte{0x05, 0x05, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+
+	go io.Copy(upstream, r)
+	io.Copy(conn, upstream)
+}
+
+func (s *socksServer) usernames() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.users...)
+}
+
+func newTCPDNSServer(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &dns.Server{
+		Listener: ln,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, m *dns.Msg) {
+			resp := new(dns.Msg)
+			resp.SetReply(m)
+			resp.Answer = []dns.RR{&dns.A{
+				Hdr: dns.RR_Header{Name: m.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.ParseIP("192.0.2.1"),
+			}}
+			w.WriteMsg(resp)
+		}),
+	}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+	return ln.Addr().String()
+}
+
+func proxyQuery(t *testing.T, c *client) {
+	t.Helper()
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	r, _, err := c.exchanger.Exchange(context.Background(), m)
+	if err != nil {
+		t.Fatalf("%s: %s", c.name, err)
+	}
+	if len(r.Answer) != 1 {
+		t.Fatalf("%s: expected 1 answer, got %v", c.name, r.Answer)
+	}
+}
+
+func TestSOCKSProxy(t *testing.T) {
+	dnsAddr := newTCPDNSServer(t)
+
+	dohSrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		m := new(dns.Msg)
+		if err := m.Unpack(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := new(dns.Msg)
+		resp.SetReply(m)
+		resp.Answer = []dns.RR{&dns.A{
+			Hdr: dns.RR_Header{Name: m.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+			A:   net.ParseIP("192.0.2.1"),
+		}}
+		b, _ := resp.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(b)
+	}))
+	defer dohSrv.Close()
+	_, dohPort, _ := net.SplitHostPort(dohSrv.Listener.Addr().String())
+
+	// The DoH server is only reachable by name, so the proxy has to do
+	// the lookup. That takes socks5h.
+	socks := newSOCKSServer(t, map[string]string{
+		"example.com:" + dohPort: dohSrv.Listener.Addr().String(),
+	})
+	proxyURL := "socks5://" + socks.ln.Addr().String()
+
+	dial, err := newProxyDialer(proxyURL, "classic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyQuery(t, newClassicClient("classic", dnsAddr, dial))
+
+	dial, err = newProxyDialer(proxyURL, "doh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dial(context.Background(), "tcp", "example.com:"+dohPort); err == nil {
+		t.Fatal("expected a socks5 proxy to refuse a host name")
+	}
+	dial, err = newProxyDialer("socks5h://"+socks.ln.Addr().String(), "doh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(dohSrv.Certificate())
+	c, err := newDOHClient("doh", "https://example.com:"+dohPort+"/dns-query", nil, doh.WithDialer(dial), doh.WithRootCAs(roots))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "example.com:" + dohPort; c.addr != expect {
+		t.Errorf("doh backend addr is %q, expected %q", c.addr, expect)
+	}
+	proxyQuery(t, c)
+
+	// Explicit credentials replace the isolation username.
+	dial, err = newProxyDialer("socks5://alice:secret@"+socks.ln.Addr().String(), "classic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyQuery(t, newClassicClient("classic", dnsAddr, dial))
+
+	users := socks.usernames()
+	expect := []string{"classic", "doh", "alice"}
+	if len(users) != len(expect) {
+		t.Fatalf("proxy saw usernames %q, expected %q", users, expect)
+	}
+	for i := range expect {
+		if users[i] != expect[i] {
+			t.Errorf("proxy saw usernames %q, expected %q", users, expect)
+			break
+		}
+	}
+}
+
+func TestHTTPConnectProxy(t *testing.T) {
+	dnsAddr := newTCPDNSServer(t)
+
+	var authz string
+	proxySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		authz = r.Header.Get("Proxy-Authorization")
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer upstream.Close()
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		go io.Copy(upstream, buf)
+		io.Copy(conn, upstream)
+	}))
+	defer proxySrv.Close()
+
+	dial, err := newProxyDialer("http://user:pass@"+proxySrv.Listener.Addr().String(), "classic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyQuery(t, newClassicClient("classic", dnsAddr, dial))
+
+	if authz != "Basic dXNlcjpwYXNz" {
+		t.Errorf("Proxy-Authorization %q, expected %q", authz, "Basic dXNlcjpwYXNz")
+	}
+
+	if _, err := newProxyDialer("ftp://127.0.0.1:21", "classic"); err == nil {
+		t.Error("expected an error for an unsupported proxy scheme")
+	}
+}
@@ -0,0 +1,502 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/psanford/dnsforward/conf"
+)
+
+const (
+	defaultQueryLogBuffer     = 4096
+	defaultQueryLogMaxSizeMB  = 100
+	defaultQueryLogMaxBackups = 10
+	rotatedTimeFormat         = "20060102T150405.000"
+
+	// rotateRetryInterval is how long to keep writing to the current
+	// file after rotating it failed.
+	rotateRetryInterval = time.Minute
+)
+
+// newQueryLog returns the writer for query log events configured by c.
+// Without a path, events go to stderr as they always have.
+func newQueryLog(c *conf.QueryLog) (*asyncWriter, error) {
+	if c == nil {
+		return newAsyncWriter(os.Stderr, defaultQueryLogBuffer), nil
+	}
+
+	bufSize := int(c.BufferEntries)
+	if bufSize == 0 {
+		bufSize = defaultQueryLogBuffer
+	}
+
+	if c.Path == "" {
+		return newAsyncWriter(os.Stderr, bufSize), nil
+	}
+
+	maxSize := int64(c.MaxSizeMb) << 20
+	if maxSize == 0 {
+		maxSize = defaultQueryLogMaxSizeMB << 20
+	}
+	maxBackups := int(c.MaxBackups)
+	if maxBackups == 0 {
+		maxBackups = defaultQueryLogMaxBackups
+	}
+
+	f := &rotatingFile{
+		path:       c.Path,
+		maxSize:    maxSize,
+		maxAge:     time.Duration(c.MaxAgeHours) * time.Hour,
+		maxBackups: maxBackups,
+		compress:   c.Compress,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return newAsyncWriter(f, bufSize), nil
+}
+
+// asyncWriter queues writes for a background goroutine so that a slow
+// destination never blocks the caller. When the queue is full new
+// writes are dropped and counted.
+type asyncWriter struct {
+	w       io.Writer
+	ch      chan []byte
+	dropped uint64
+	done    chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newAsyncWriter(w io.Writer, size int) *asyncWriter {
+	a := &asyncWriter{
+		w:    w,
+		ch:   make(chan []byte, size),
+		done: make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Write queues a copy of p. It never blocks and always reports success.
+// Writes after Close are discarded.
+func (a *asyncWriter) Write(p []byte) (int, error) {
+	b := make([]byte, len(p))
+	copy(b, p)
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return len(p), nil
+	}
+
+	select {
+	case a.ch <- b:
+	default:
+		atomic.AddUint64(&a.dropped, 1)
+	}
+	return len(p), nil
+}
+
+func (a *asyncWriter) run() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case b, ok := <-a.ch:
+			if !ok {
+				return
+			}
+			if _, err := a.w.Write(b); err != nil {
+				log.Printf("query log write error: %s", err)
+			}
+		case <-ticker.C:
+			if n := atomic.SwapUint64(&a.dropped, 0); n > 0 {
+				log.Printf("query log is falling behind, dropped %d entries", n)
+			}
+		}
+	}
+}
+
+// Close flushes queued writes and closes the underlying writer if it is
+// an io.Closer.
+func (a *asyncWriter) Close() error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.ch)
+	}
+	a.mu.Unlock()
+
+	<-a.done
+	if c, ok := a.w.(io.Closer); ok && a.w != os.Stderr {
+		return c.Close()
+	}
+	return nil
+}
+
+// rotatingFile is a log file that is renamed aside when it grows past
+// maxSize or has been open longer than maxAge. Rotated files are named
+// <path>.<timestamp>, optionally gzipped, and only the newest maxBackups
+// are kept.
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+
+	f       *os.File
+	size    int64
+	opened  time.Time
+	retryAt time.Time // no rotation is attempted before this
+
+	// cleanup serializes compressing and pruning rotated files, which
+	// happens in the background. pending tracks cleanups not yet done.
+	cleanup sync.Mutex
+	pending sync.WaitGroup
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f = f
+	r.size = fi.Size()
+	// A file left by an earlier run is aged from its last write, so a
+	// restart doesn't start its max age over.
+	r.opened = time.Now()
+	if r.size > 0 {
+		r.opened = fi.ModTime()
+	}
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	if r.size > 0 && (r.size+int64(len(p)) > r.maxSize || (r.maxAge > 0 && time.Since(r.opened) > r.maxAge)) && !time.Now().Before(r.retryAt) {
+		r.rotate()
+	}
+
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate renames the current file aside and starts a new one. If that
+// fails, writes carry on to the current file and rotation is retried
+// after rotateRetryInterval.
+func (r *rotatingFile) rotate() {
+	// Small files can rotate more than once a millisecond; never clobber
+	// an earlier rotated file.
+	ts := time.Now()
+	rotated := r.path + "." + ts.Format(rotatedTimeFormat)
+	for fileExists(rotated) || fileExists(rotated+".gz") {
+		ts = ts.Add(time.Millisecond)
+		rotated = r.path + "." + ts.Format(rotatedTimeFormat)
+	}
+	if err := os.Rename(r.path, rotated); err != nil {
+		log.Printf("query log rotate error: %s", err)
+		r.retryAt = time.Now().Add(rotateRetryInterval)
+		if os.IsNotExist(err) {
+			// The file was removed out from under us.
+			r.reopen()
+		}
+		return
+	}
+
+	if !r.reopen() {
+		// Still writing to the rotated file, so leave it alone.
+		r.retryAt = time.Now().Add(rotateRetryInterval)
+		return
+	}
+
+	r.pending.Add(1)
+	go func() {
+		defer r.pending.Done()
+		r.cleanup.Lock()
+		defer r.cleanup.Unlock()
+
+		if r.compress {
+			if err := gzipFile(rotated); err != nil {
+				log.Printf("query log compress %s error: %s", rotated, err)
+			}
+		}
+		r.prune()
+	}()
+}
+
+// reopen switches to a new file at path. The current file is kept if
+// that fails.
+func (r *rotatingFile) reopen() bool {
+	old := r.f
+	if err := r.open(); err != nil {
+		log.Printf("query log open error: %s", err)
+		return false
+	}
+	old.Close()
+	return true
+}
+
+// prune removes the oldest rotated files beyond maxBackups.
+func (r *rotatingFile) prune() {
+	matches, err := filepath.Glob(r.path + ".*")
+	if err != nil {
+		return
+	}
+
+	var backups []string
+	prefix := r.path + "."
+	for _, m := range matches {
+		ts := strings.TrimSuffix(strings.TrimPrefix(m, prefix), ".gz")
+		if _, err := time.Parse(rotatedTimeFormat, ts); err == nil {
+			backups = append(backups, m)
+		}
+	}
+
+	// the timestamp format sorts chronologically
+	sort.Strings(backups)
+	for len(backups) > r.maxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			log.Printf("query log prune error: %s", err)
+		}
+		backups = backups[1:]
+	}
+}
+
+func (r *rotatingFile) Close() error {
+	r.pending.Wait()
+	return r.f.Close()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func gzipFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return fmt.Errorf("close %s: %w", out.Name(), err)
+	}
+
+	return os.Remove(path)
+}
+
+// logFieldSet limits query log events to a configured set of fields. ts,
+// evt and id are always kept so events can still be told apart and
+// joined.
+type logFieldSet struct {
+	names map[string]bool
+	// types holds the kept fields of each of logEventTypes, worked out
+	// up front so an event is encoded only once.
+	types map[reflect.Type][]logField
+}
+
+// logField is a field of an event struct as encoding/json sees it.
+type logField struct {
+	name      string
+	key       []byte // the quoted name and colon
+	index     []int
+	omitEmpty bool
+}
+
+// logEventTypes lists every event written to the query log; it is used
+// to validate configured field names.
+var logEventTypes = []interface{}{
+	logRequest{},
+	logResultMsg{},
+	logFirstResultMsg{},
+	logFailureMsg{},
+	logResponseMsg{},
+	logCoalescedMsg{},
+	logCachedResultMsg{},
+	logValidationMsg{},
+	logRebindMsg{},
+	logPinMismatchMsg{},
+}
+
+func newLogFieldSet(fields []string) (*logFieldSet, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]bool)
+	for _, evt := range logEventTypes {
+		for _, f := range jsonFields(reflect.TypeOf(evt), nil) {
+			known[f.name] = true
+		}
+	}
+
+	s := &logFieldSet{
+		names: map[string]bool{"ts": true, "evt": true, "id": true},
+		types: make(map[reflect.Type][]logField),
+	}
+	for _, f := range fields {
+		if !known[f] {
+			return nil, fmt.Errorf("unknown query log field %q", f)
+		}
+		s.names[f] = true
+	}
+	for _, evt := range logEventTypes {
+		t := reflect.TypeOf(evt)
+		s.types[t] = s.kept(t)
+	}
+	return s, nil
+}
+
+// kept returns the fields of struct type t that are in the set.
+func (s *logFieldSet) kept(t reflect.Type) []logField {
+	var kept []logField
+	for _, f := range jsonFields(t, nil) {
+		if s.names[f.name] {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// jsonFields returns the fields of struct type t, including those
+// promoted from embedded structs, in the order encoding/json writes
+// them. index is the path from the outer struct to t.
+func jsonFields(t reflect.Type, index []int) []logField {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var fields []logField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fieldIndex := append(index[:len(index):len(index)], i)
+		if f.Anonymous {
+			fields = append(fields, jsonFields(f.Type, fieldIndex)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, logField{
+			name:      name,
+			key:       []byte(strconv.Quote(name) + ":"),
+			index:     fieldIndex,
+			omitEmpty: strings.Contains(","+opts+",", ",omitempty,"),
+		})
+	}
+	return fields
+}
+
+// filter returns evt with only the fields in the set.
+func (s *logFieldSet) filter(evt interface{}) interface{} {
+	v := reflect.ValueOf(evt)
+	if v.Kind() != reflect.Struct {
+		return evt
+	}
+	fields, ok := s.types[v.Type()]
+	if !ok {
+		fields = s.kept(v.Type())
+	}
+	return filteredEvent{v: v, fields: fields}
+}
+
+// filteredEvent encodes the given fields of the event v.
+type filteredEvent struct {
+	v      reflect.Value
+	fields []logField
+}
+
+func (e filteredEvent) MarshalJSON() ([]byte, error) {
+	b := []byte{'{'}
+	for _, f := range e.fields {
+		fv, err := e.v.FieldByIndexErr(f.index)
+		if err != nil {
+			// Promoted from a nil embedded pointer, which encoding/json
+			// leaves out as well.
+			continue
+		}
+		if f.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		if len(b) > 1 {
+			b = append(b, ',')
+		}
+		b = append(b, f.key...)
+		switch fv.Kind() {
+		case reflect.Bool:
+			b = strconv.AppendBool(b, fv.Bool())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			b = strconv.AppendInt(b, fv.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			b = strconv.AppendUint(b, fv.Uint(), 10)
+		default:
+			val, err := json.Marshal(fv.Interface())
+			if err != nil {
+				return nil, err
+			}
+			b = append(b, val...)
+		}
+	}
+	return append(b, '}'), nil
+}
+
+// isEmptyValue reports whether omitempty drops v.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
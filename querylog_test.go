@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+)
+
+func TestQueryLogRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "query.log")
+
+	w, err := newQueryLog(&conf.QueryLog{
+		Path:       path,
+		MaxBackups: 2,
+		Compress:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rotate every few lines instead of every 100MB
+	w.w.(*rotatingFile).maxSize = 100
+
+	line := strings.Repeat("x", 39) + "\n"
+	for i := 0; i < 20; i++ {
+		fmt.Fprint(w, line)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(current) == 0 || len(current) > 100 {
+		t.Errorf("current log is %d bytes", len(current))
+	}
+
+	rotated, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 2 {
+		t.Fatalf("expected 2 rotated files, got %v", rotated)
+	}
+	for _, p := range rotated {
+		if !strings.HasSuffix(p, ".gz") {
+			t.Errorf("rotated file %s was not compressed", p)
+			continue
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(zr)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) == 0 || len(b)%len(line) != 0 {
+			t.Errorf("rotated file %s has %d bytes", p, len(b))
+		}
+	}
+}
+
+func TestQueryLogRotationErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "query.log")
+
+	// A log carried over from a previous run keeps its age.
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	r := &rotatingFile{path: path, maxSize: 100, maxAge: time.Hour, maxBackups: 2}
+	if err := r.open(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]byte("new\n")); err != nil {
+		t.Fatal(err)
+	}
+	r.pending.Wait()
+	if b, _ := os.ReadFile(path); string(b) != "new\n" {
+		t.Errorf("expected the old log to be rotated, current log is %q", b)
+	}
+
+	// A failed rename keeps the log going instead of failing writes.
+	line := strings.Repeat("x", 59) + "\n"
+	if _, err := r.Write([]byte(line)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Write([]byte(line)); err != nil {
+		t.Fatalf("write after failed rotation: %s", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != line {
+		t.Errorf("expected a new log with one line, got %q", b)
+	}
+	r.f.Close()
+}
+
+func TestQueryLogFields(t *testing.T) {
+	s := newTestServer(conf.Config_InOrder, exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		return nil, 0, errors.New("backend down")
+	}), exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		return answerA(m, "192.0.2.1"), 0, nil
+	}))
+	var buf bytes.Buffer
+	s.logStream = json.NewEncoder(&buf)
+	s.logQueries.Store(true)
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeAAAA)
+	req.SetEdns0(1232, true)
+	s.handleRequest(&recorder{}, req)
+
+	events := make(map[string]map[string]interface{})
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var e map[string]interface{}
+		if err := dec.Decode(&e); err != nil {
+			t.Fatal(err)
+		}
+		events[e["evt"].(string)] = e
+	}
+
+	expect := map[string]map[string]interface{}{
+		"request": {
+			"client_addr":   "127.0.0.1:40000",
+			"transport":     "udp",
+			"qname":         "example.com.",
+			"qtype":         "AAAA",
+			"edns_udp_size": 1232.0,
+			"edns_do":       true,
+		},
+		"backend_result": {
+			"error": "backend down",
+		},
+		"response": {
+			"rcode":    "NOERROR",
+			"answers":  1.0,
+			"override": false,
+		},
+	}
+	for evt, fields := range expect {
+		for k, v := range fields {
+			if got := events[evt][k]; got != v {
+				t.Errorf("%s %s = %v, expected %v", evt, k, got, v)
+			}
+		}
+	}
+
+	s.logFields, _ = newLogFieldSet([]string{"qname", "rcode"})
+	buf.Reset()
+	s.handleRequest(&recorder{}, req)
+
+	var e map[string]interface{}
+	if err := json.NewDecoder(&buf).Decode(&e); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if got := strings.Join(keys, ","); got != "evt,id,qname,ts" {
+		t.Errorf("filtered request event has fields %s", got)
+	}
+
+	if _, err := newLogFieldSet([]string{"nope"}); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+
+	// Filtering matches encoding the whole event and dropping the other
+	// keys, including omitempty fields and ones promoted from a nil
+	// *logAnswer.
+	set, err := newLogFieldSet([]string{"qname", "rcode", "error", "edns_do", "blocked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for _, evt := range []interface{}{
+		logResultMsg{TS: now, Evt: "backend_result", ID: "1", Error: "timeout", logQuestion: logQuestion{QName: "example.com."}},
+		logResponseMsg{TS: now, Evt: "response", ID: "2", logAnswer: &logAnswer{Rcode: "NOERROR"}},
+		logResponseMsg{TS: now, Evt: "response", ID: "3"},
+		logRequest{TS: now, Evt: "request", ID: "4", EDNSDo: false},
+		logRebindMsg{TS: now, Evt: "rebind_blocked", ID: "5", Blocked: []string{"10.0.0.1"}},
+	} {
+		full, err := json.Marshal(evt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expect map[string]json.RawMessage
+		json.Unmarshal(full, &expect)
+		for k := range expect {
+			if !set.names[k] {
+				delete(expect, k)
+			}
+		}
+		want, _ := json.Marshal(expect)
+
+		b, err := json.Marshal(set.filter(evt))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var filtered map[string]json.RawMessage
+		if err := json.Unmarshal(b, &filtered); err != nil {
+			t.Fatalf("%T: %s: %s", evt, b, err)
+		}
+		got, _ := json.Marshal(filtered)
+		if string(got) != string(want) {
+			t.Errorf("%T: filtered to %s, expected %s", evt, got, want)
+		}
+	}
+}
+
+func BenchmarkLogFieldSet(b *testing.B) {
+	evt := logResultMsg{
+		TS:          time.Now(),
+		Evt:         "backend_result",
+		ID:          "12345",
+		DurationUS:  1234,
+		Backend:     "cloudflare",
+		Mode:        "doh",
+		BackendAddr: "1.1.1.1:443",
+		Req:         ";; opcode: QUERY, status: NOERROR, id: 1234",
+		logQuestion: logQuestion{QName: "example.com.", QType: "A", QClass: "IN"},
+		logAnswer:   &logAnswer{Rcode: "NOERROR", Answers: 1, RespSize: 56},
+	}
+	enc := json.NewEncoder(io.Discard)
+
+	b.Run("unfiltered", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			enc.Encode(evt)
+		}
+	})
+	b.Run("filtered", func(b *testing.B) {
+		set, err := newLogFieldSet([]string{"qname", "qtype", "rcode", "backend", "duration_us"})
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			enc.Encode(set.filter(evt))
+		}
+	})
+}
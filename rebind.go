@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+)
+
+var defaultRebindCIDRs = []string{
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"::/128",
+	"::1/128",
+	"fc00::/7",
+	"fe80::/10",
+}
+
+// rebindFilter keeps public names from resolving to addresses on the
+// local network, which would let a malicious site's scripts reach
+// internal services through the browser.
+type rebindFilter struct {
+	refuse  bool
+	blocked []*net.IPNet
+	allowed []string // lower case fqdns
+}
+
+func newRebindFilter(c *conf.RebindProtection) (*rebindFilter, error) {
+	cidrs := c.BlockedCidrs
+	if len(cidrs) == 0 {
+		cidrs = defaultRebindCIDRs
+	}
+
+	f := &rebindFilter{
+		refuse: c.Action == conf.RebindProtection_REFUSE,
+	}
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("blocked_cidrs: %w", err)
+		}
+		f.blocked = append(f.blocked, n)
+	}
+	for _, d := range c.AllowedDomains {
+		if _, ok := dns.IsDomainName(d); !ok {
+			return nil, fmt.Errorf("allowed_domains: invalid domain %q", d)
+		}
+		f.allowed = append(f.allowed, strings.ToLower(dns.Fqdn(d)))
+	}
+	return f, nil
+}
+
+func (f *rebindFilter) allowedName(name string) bool {
+	name = strings.ToLower(name)
+	for _, d := range f.allowed {
+		if dns.IsSubDomain(d, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *rebindFilter) blockedIP(ip net.IP) bool {
+	for _, n := range f.blocked {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter checks the addresses in resp, the upstream answer to r,
+// including SVCB and HTTPS address hints. If any are blocked it returns
+// the response to send instead and the blocked addresses; otherwise it
+// returns resp unchanged. isOverride reports whether a name has a local
+// override entry.
+func (f *rebindFilter) filter(r, resp *dns.Msg, isOverride func(string) bool) (*dns.Msg, []net.IP) {
+	// Only the name the client asked for can be exempt. A public name
+	// with a CNAME into an allowed domain is exactly how a rebinding
+	// attack would get through.
+	for _, q := range r.Question {
+		if f.allowedName(q.Name) || isOverride(q.Name) {
+			return resp, nil
+		}
+	}
+
+	var blocked []net.IP
+	keep := make([]dns.RR, 0, len(resp.Answer))
+	for _, rr := range resp.Answer {
+		switch rr := rr.(type) {
+		case *dns.A:
+			if f.blockedIP(rr.A) {
+				blocked = append(blocked, rr.A)
+				continue
+			}
+		case *dns.AAAA:
+			if f.blockedIP(rr.AAAA) {
+				blocked = append(blocked, rr.AAAA)
+				continue
+			}
+		case *dns.SVCB:
+			if svcb, hints := f.filterHints(rr); len(hints) > 0 {
+				blocked = append(blocked, hints...)
+				keep = append(keep, svcb)
+				continue
+			}
+		case *dns.HTTPS:
+			if svcb, hints := f.filterHints(&rr.SVCB); len(hints) > 0 {
+				blocked = append(blocked, hints...)
+				keep = append(keep, &dns.HTTPS{SVCB: *svcb})
+				continue
+			}
+		}
+		keep = append(keep, rr)
+	}
+	if len(blocked) == 0 {
+		return resp, nil
+	}
+
+	if f.refuse {
+		out := new(dns.Msg)
+		out.SetRcode(r, dns.RcodeRefused)
+		if opt := r.IsEdns0(); opt != nil {
+			out.SetEdns0(opt.UDPSize(), opt.Do())
+			out.IsEdns0().Option = append(out.IsEdns0().Option, &dns.EDNS0_EDE{
+				InfoCode:  dns.ExtendedErrorCodeBlocked,
+				ExtraText: "answer contains a private address",
+			})
+		}
+		return out, blocked
+	}
+
+	out := resp.Copy()
+	out.Answer = keep
+	// The remaining records no longer match their signatures.
+	out.AuthenticatedData = false
+	return out, blocked
+}
+
+// filterHints returns a copy of rr without blocked ipv4hint and ipv6hint
+// addresses, and the addresses removed. rr is returned as is if nothing
+// was removed.
+func (f *rebindFilter) filterHints(rr *dns.SVCB) (*dns.SVCB, []net.IP) {
+	var blocked []net.IP
+	allowed := func(ips []net.IP) []net.IP {
+		var keep []net.IP
+		for _, ip := range ips {
+			if f.blockedIP(ip) {
+				blocked = append(blocked, ip)
+			} else {
+				keep = append(keep, ip)
+			}
+		}
+		return keep
+	}
+
+	values := make([]dns.SVCBKeyValue, 0, len(rr.Value))
+	for _, kv := range rr.Value {
+		switch kv := kv.(type) {
+		case *dns.SVCBIPv4Hint:
+			if hint := allowed(kv.Hint); len(hint) > 0 {
+				values = append(values, &dns.SVCBIPv4Hint{Hint: hint})
+			}
+			continue
+		case *dns.SVCBIPv6Hint:
+			if hint := allowed(kv.Hint); len(hint) > 0 {
+				values = append(values, &dns.SVCBIPv6Hint{Hint: hint})
+			}
+			continue
+		}
+		values = append(values, kv)
+	}
+	if len(blocked) == 0 {
+		return rr, nil
+	}
+
+	out := *rr
+	out.Value = values
+	return &out, blocked
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+)
+
+func TestRebindProtection(t *testing.T) {
+	answers := map[string]string{
+		"evil.example.com.":   "192.168.1.1",
+		"cgnat.example.com.":  "100.64.0.1",
+		"public.example.com.": "93.184.216.34",
+		"router.lan.":         "192.168.1.1",
+		"printer.example.":    "10.0.0.5",
+	}
+	backend := exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		return answerA(m, answers[m.Question[0].Name]), 0, nil
+	})
+
+	for _, action := range []conf.RebindProtection_Action{conf.RebindProtection_STRIP, conf.RebindProtection_REFUSE} {
+		s := newTestServer(conf.Config_InOrder, backend)
+		s.localOverrides.set("printer.example.", "10.0.0.5")
+		f, err := newRebindFilter(&conf.RebindProtection{
+			Action:         action,
+			AllowedDomains: []string{"lan"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.rebind = f
+
+		for name := range answers {
+			qtype := dns.TypeA
+			if name == "printer.example." {
+				// The override only covers A, so AAAA goes upstream.
+				qtype = dns.TypeAAAA
+			}
+			req := new(dns.Msg)
+			req.SetQuestion(name, qtype)
+			w := &recorder{}
+			s.handleRequest(w, req)
+
+			blocked := name == "evil.example.com." || name == "cgnat.example.com."
+			switch {
+			case !blocked:
+				if w.msg.Rcode != dns.RcodeSuccess || len(w.msg.Answer) != 1 {
+					t.Errorf("%s %s: allowed answer was filtered: %s", action, name, w.msg)
+				}
+			case action == conf.RebindProtection_REFUSE:
+				if w.msg.Rcode != dns.RcodeRefused {
+					t.Errorf("%s %s: rcode %s, expected REFUSED", action, name, dns.RcodeToString[w.msg.Rcode])
+				}
+			default:
+				if w.msg.Rcode != dns.RcodeSuccess || len(w.msg.Answer) != 0 {
+					t.Errorf("%s %s: blocked address was not stripped: %s", action, name, w.msg)
+				}
+			}
+		}
+	}
+}
+
+func TestRebindIndirect(t *testing.T) {
+	backend := exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		resp := new(dns.Msg)
+		resp.SetReply(m)
+		switch m.Question[0].Name {
+		case "alias.example.com.":
+			resp.Answer = []dns.RR{
+				testRR(t, "alias.example.com. 60 IN CNAME router.lan."),
+				testRR(t, "router.lan. 60 IN A 192.168.1.1"),
+			}
+		case "svc.example.com.":
+			resp.Answer = []dns.RR{
+				testRR(t, `svc.example.com. 60 IN HTTPS 1 . alpn="h2" ipv4hint="192.168.1.1,93.184.216.34" ipv6hint="fd00::1"`),
+			}
+		}
+		return resp, 0, nil
+	})
+
+	s := newTestServer(conf.Config_InOrder, backend)
+	f, err := newRebindFilter(&conf.RebindProtection{AllowedDomains: []string{"lan"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.rebind = f
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		req := new(dns.Msg)
+		req.SetQuestion(name, qtype)
+		w := &recorder{}
+		s.handleRequest(w, req)
+		return w.msg
+	}
+
+	// The CNAME target is allowed, but the client asked for a public
+	// name.
+	resp := query("alias.example.com.", dns.TypeA)
+	for _, rr := range resp.Answer {
+		if _, ok := rr.(*dns.A); ok {
+			t.Errorf("private address behind a CNAME to an allowed domain was not stripped: %s", rr)
+		}
+	}
+
+	resp = query("svc.example.com.", dns.TypeHTTPS)
+	if len(resp.Answer) != 1 {
+		t.Fatalf("expected the HTTPS record to be kept, got %v", resp.Answer)
+	}
+	expect := `svc.example.com.	60	IN	HTTPS	1 . alpn="h2" ipv4hint="93.184.216.34"`
+	if got := resp.Answer[0].String(); got != expect {
+		t.Errorf("got %s, expected %s", got, expect)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ewmaWeight is the weight given to the newest sample in the moving
+// average of backend latency.
+const ewmaWeight = 0.1
+
+// backendStats tracks the health and latency of a single backend.
+type backendStats struct {
+	mu          sync.Mutex
+	queries     uint64
+	errors      uint64
+	total       time.Duration
+	ewma        time.Duration
+	lastError   string
+	lastErrorAt time.Time
+	lastOKAt    time.Time
+}
+
+func (b *backendStats) record(d time.Duration, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.queries++
+	if err != nil {
+		b.errors++
+		b.lastError = err.Error()
+		b.lastErrorAt = time.Now()
+		return
+	}
+
+	b.lastOKAt = time.Now()
+	b.total += d
+	if b.ewma == 0 {
+		b.ewma = d
+	} else {
+		b.ewma = time.Duration(ewmaWeight*float64(d) + (1-ewmaWeight)*float64(b.ewma))
+	}
+}
+
+type backendStatsSnapshot struct {
+	Queries       uint64     `json:"queries"`
+	Errors        uint64     `json:"errors"`
+	AvgLatencyUS  int64      `json:"avg_latency_us"`
+	EWMALatencyUS int64      `json:"ewma_latency_us"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+	LastOKAt      *time.Time `json:"last_ok_at,omitempty"`
+	Healthy       bool       `json:"healthy"`
+}
+
+func (b *backendStats) snapshot() backendStatsSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	snap := backendStatsSnapshot{
+		Queries:       b.queries,
+		Errors:        b.errors,
+		EWMALatencyUS: b.ewma.Microseconds(),
+		LastError:     b.lastError,
+		// a backend is healthy until its most recent query failed
+		Healthy: b.lastErrorAt.IsZero() || b.lastOKAt.After(b.lastErrorAt),
+	}
+	if ok := b.queries - b.errors; ok > 0 {
+		snap.AvgLatencyUS = (b.total / time.Duration(ok)).Microseconds()
+	}
+	if !b.lastErrorAt.IsZero() {
+		t := b.lastErrorAt
+		snap.LastErrorAt = &t
+	}
+	if !b.lastOKAt.IsZero() {
+		t := b.lastOKAt
+		snap.LastOKAt = &t
+	}
+	return snap
+}
+
+// percentile returns the p-th percentile (0-100) of sorted using the
+// nearest rank method. sorted must be in ascending order.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+func sortDurations(d []time.Duration) {
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+}
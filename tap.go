@@ -0,0 +1,314 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+	"github.com/psanford/dnsforward/dnstap"
+)
+
+const (
+	tapBufferSize     = 4096
+	tapFlushInterval  = time.Second
+	tapReconnectDelay = 5 * time.Second
+)
+
+// tap sends dnstap messages for client and forwarder queries and
+// responses to a collector. Messages are queued and written in the
+// background; when the collector can't keep up or is unreachable new
+// messages are dropped. A nil *tap discards everything.
+type tap struct {
+	socketPath string
+	filePath   string
+	identity   []byte
+	version    []byte
+
+	ch   chan *dnstap.Dnstap
+	done chan struct{}
+	exit chan struct{}
+}
+
+func newTap(c *conf.Dnstap) (*tap, error) {
+	if c.SocketPath == "" && c.FilePath == "" {
+		return nil, errors.New("dnstap requires socket_path or file_path")
+	}
+
+	identity := c.Identity
+	if identity == "" {
+		identity, _ = os.Hostname()
+	}
+
+	t := &tap{
+		socketPath: c.SocketPath,
+		filePath:   c.FilePath,
+		identity:   []byte(identity),
+		version:    []byte("dnsforward"),
+		ch:         make(chan *dnstap.Dnstap, tapBufferSize),
+		done:       make(chan struct{}),
+		exit:       make(chan struct{}),
+	}
+	go t.run()
+	return t, nil
+}
+
+func (t *tap) clientQuery(w dns.ResponseWriter, r *dns.Msg, queryTime time.Time) {
+	if t == nil {
+		return
+	}
+	m := t.clientMessage(dnstap.Message_CLIENT_QUERY, w)
+	setQuery(m, r, queryTime)
+	t.send(m)
+}
+
+func (t *tap) clientResponse(w dns.ResponseWriter, r, resp *dns.Msg, queryTime time.Time) {
+	if t == nil {
+		return
+	}
+	m := t.clientMessage(dnstap.Message_CLIENT_RESPONSE, w)
+	setQuery(m, r, queryTime)
+	setResponse(m, resp, time.Now())
+	t.send(m)
+}
+
+func (t *tap) forwarderQuery(c *client, addr string, tcp bool, r *dns.Msg, queryTime time.Time) {
+	if t == nil {
+		return
+	}
+	m := t.forwarderMessage(dnstap.Message_FORWARDER_QUERY, c, addr, tcp)
+	setQuery(m, r, queryTime)
+	t.send(m)
+}
+
+func (t *tap) forwarderResponse(c *client, addr string, tcp bool, r, resp *dns.Msg, queryTime time.Time) {
+	if t == nil || resp == nil {
+		return
+	}
+	m := t.forwarderMessage(dnstap.Message_FORWARDER_RESPONSE, c, addr, tcp)
+	setQuery(m, r, queryTime)
+	setResponse(m, resp, time.Now())
+	t.send(m)
+}
+
+func (t *tap) clientMessage(typ dnstap.Message_Type, w dns.ResponseWriter) *dnstap.Message {
+	m := &dnstap.Message{Type: &typ}
+
+	proto := dnstap.SocketProtocol_UDP
+	if _, ok := w.RemoteAddr().(*net.TCPAddr); ok {
+		proto = dnstap.SocketProtocol_TCP
+	}
+	m.SocketProtocol = &proto
+
+	if ip, port := addrIPPort(w.RemoteAddr()); ip != nil {
+		setFamily(m, ip)
+		m.QueryAddress = ip
+		m.QueryPort = &port
+	}
+	if ip, port := addrIPPort(w.LocalAddr()); ip != nil {
+		m.ResponseAddress = ip
+		m.ResponsePort = &port
+	}
+	return m
+}
+
+// forwarderMessage returns a message for an exchange with backend c at
+// addr, the host:port the query was sent to. tcp is set when a classic
+// query went over tcp.
+func (t *tap) forwarderMessage(typ dnstap.Message_Type, c *client, addr string, tcp bool) *dnstap.Message {
+	m := &dnstap.Message{Type: &typ}
+
+	proto := dnstap.SocketProtocol_UDP
+	if c.mode == dohTransitMode {
+		proto = dnstap.SocketProtocol_DOH
+	} else if tcp {
+		proto = dnstap.SocketProtocol_TCP
+	}
+	m.SocketProtocol = &proto
+
+	if host, portStr, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(host); ip != nil {
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			setFamily(m, ip)
+			m.ResponseAddress = ip
+			if port, err := net.LookupPort("tcp", portStr); err == nil {
+				p := uint32(port)
+				m.ResponsePort = &p
+			}
+		}
+	}
+	return m
+}
+
+func addrIPPort(addr net.Addr) (net.IP, uint32) {
+	var (
+		ip   net.IP
+		port int
+	)
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		ip, port = a.IP, a.Port
+	case *net.TCPAddr:
+		ip, port = a.IP, a.Port
+	default:
+		return nil, 0
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	return ip, uint32(port)
+}
+
+func setFamily(m *dnstap.Message, ip net.IP) {
+	family := dnstap.SocketFamily_INET6
+	if len(ip) == net.IPv4len {
+		family = dnstap.SocketFamily_INET
+	}
+	m.SocketFamily = &family
+}
+
+func setQuery(m *dnstap.Message, r *dns.Msg, at time.Time) {
+	// Pack writes to the OPT record, so pack a copy to avoid racing with
+	// other users of r.
+	if b, err := r.Copy().Pack(); err == nil {
+		m.QueryMessage = b
+	}
+	sec, nsec := uint64(at.Unix()), uint32(at.Nanosecond())
+	m.QueryTimeSec = &sec
+	m.QueryTimeNsec = &nsec
+}
+
+func setResponse(m *dnstap.Message, resp *dns.Msg, at time.Time) {
+	if b, err := resp.Copy().Pack(); err == nil {
+		m.ResponseMessage = b
+	}
+	sec, nsec := uint64(at.Unix()), uint32(at.Nanosecond())
+	m.ResponseTimeSec = &sec
+	m.ResponseTimeNsec = &nsec
+}
+
+func (t *tap) send(m *dnstap.Message) {
+	typ := dnstap.Dnstap_MESSAGE
+	d := &dnstap.Dnstap{
+		Identity: t.identity,
+		Version:  t.version,
+		Type:     &typ,
+		Message:  m,
+	}
+	select {
+	case t.ch <- d:
+	default:
+	}
+}
+
+// close flushes queued messages and ends the stream.
+func (t *tap) close() {
+	if t == nil {
+		return
+	}
+	close(t.done)
+	select {
+	case <-t.exit:
+	case <-time.After(5 * time.Second):
+	}
+}
+
+func (t *tap) run() {
+	defer close(t.exit)
+
+	for {
+		conn, fw, err := t.connect()
+		if err != nil {
+			log.Printf("dnstap connect error: %s", err)
+			select {
+			case <-time.After(tapReconnectDelay):
+				continue
+			case <-t.done:
+				return
+			}
+		}
+
+		err = t.stream(fw)
+		conn.Close()
+		if err == nil {
+			return
+		}
+		log.Printf("dnstap write error: %s", err)
+	}
+}
+
+func (t *tap) connect() (io.Closer, *dnstap.Writer, error) {
+	if t.socketPath != "" {
+		conn, err := net.DialTimeout("unix", t.socketPath, tapReconnectDelay)
+		if err != nil {
+			return nil, nil, err
+		}
+		conn.SetDeadline(time.Now().Add(tapReconnectDelay))
+		fw, err := dnstap.NewBidirectionalWriter(conn)
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		conn.SetDeadline(time.Time{})
+		return conn, fw, nil
+	}
+
+	// Append, so neither a reconnect after a write error nor a restart
+	// throws away what was already written. Each connect starts a new
+	// frame stream in the file.
+	f, err := os.OpenFile(t.filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	fw, err := dnstap.NewWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, fw, nil
+}
+
+// stream writes queued messages to fw until the tap is closed, which
+// returns nil, or a write fails.
+func (t *tap) stream(fw *dnstap.Writer) error {
+	ticker := time.NewTicker(tapFlushInterval)
+	defer ticker.Stop()
+
+	write := func(d *dnstap.Dnstap) error {
+		b, err := d.Marshal()
+		if err != nil {
+			return nil
+		}
+		return fw.WriteFrame(b)
+	}
+
+	for {
+		select {
+		case d := <-t.ch:
+			if err := write(d); err != nil {
+				return err
+			}
+		case <-ticker.C:
+			if err := fw.Flush(); err != nil {
+				return err
+			}
+		case <-t.done:
+			for {
+				select {
+				case d := <-t.ch:
+					if err := write(d); err != nil {
+						return err
+					}
+				default:
+					return fw.Close()
+				}
+			}
+		}
+	}
+}
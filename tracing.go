@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const defaultTracingEndpoint = "localhost:4318"
+
+// tracer creates the query and backend spans. Until setupTracing
+// installs a provider it is a no-op.
+var tracer = otel.Tracer("github.com/psanford/dnsforward")
+
+// setupTracing installs a global tracer provider that exports spans over
+// OTLP/HTTP, and the W3C trace context propagator used to pass the trace
+// on to DoH backends. The returned function flushes and stops the
+// exporter.
+func setupTracing(c *conf.Tracing) (func(context.Context) error, error) {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = defaultTracingEndpoint
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+	if c.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := c.ServiceName
+	if serviceName == "" {
+		serviceName = "dnsforward"
+	}
+
+	sampler := sdktrace.AlwaysSample()
+	if c.SamplePercent > 0 && c.SamplePercent < 100 {
+		sampler = sdktrace.TraceIDRatioBased(float64(c.SamplePercent) / 100)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return tp.Shutdown, nil
+}
+
+// questionAttributes describes the question of r for a span.
+func questionAttributes(r *dns.Msg) []attribute.KeyValue {
+	if len(r.Question) == 0 {
+		return nil
+	}
+	q := r.Question[0]
+	return []attribute.KeyValue{
+		attribute.String("dns.qname", q.Name),
+		attribute.String("dns.qtype", dns.Type(q.Qtype).String()),
+	}
+}
+
+// setOutcome records where the answer to a query came from: "override",
+// "cache", "stale", "backend" or "failure".
+func setOutcome(ctx context.Context, outcome string) {
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("dns.outcome", outcome))
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/psanford/dnsforward/conf"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+
+	s := newTestServer(conf.Config_Concurrent, exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		return nil, 0, errors.New("backend down")
+	}), exchangeFunc(func(ctx context.Context, m *dns.Msg) (*dns.Msg, time.Duration, error) {
+		return answerA(m, "192.0.2.1"), 0, nil
+	}))
+
+	req := new(dns.Msg)
+	req.SetQuestion("example.com.", dns.TypeA)
+	s.handleRequest(&recorder{}, req)
+
+	// The losing backend may still be running after the response was
+	// written.
+	deadline := time.Now().Add(time.Second)
+	for len(rec.Ended()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	spans := rec.Ended()
+	var query sdktrace.ReadOnlySpan
+	backends := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range spans {
+		switch span.Name() {
+		case "dns.query":
+			query = span
+		case "backend.exchange":
+			backends[spanAttr(span, "dns.backend")] = span
+		}
+	}
+	if query == nil || len(backends) != 2 {
+		t.Fatalf("got %d spans, expected a query span and 2 backend spans", len(spans))
+	}
+
+	if got := spanAttr(query, "dns.outcome"); got != "backend" {
+		t.Errorf("outcome %q, expected backend", got)
+	}
+	if got := spanAttr(query, "dns.rcode"); got != "NOERROR" {
+		t.Errorf("rcode %q, expected NOERROR", got)
+	}
+
+	for name, span := range backends {
+		if span.Parent().SpanID() != query.SpanContext().SpanID() {
+			t.Errorf("%s span is not a child of the query span", name)
+		}
+		if got := spanAttr(span, "dns.transit"); got != "classic" {
+			t.Errorf("%s transit %q, expected classic", name, got)
+		}
+	}
+	if len(backends["backend-0"].Events()) == 0 {
+		t.Error("failed backend span has no error event")
+	}
+	if got := spanAttr(backends["backend-1"], "dns.rcode"); got != "NOERROR" {
+		t.Errorf("backend-1 rcode %q, expected NOERROR", got)
+	}
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key attribute.Key) string {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
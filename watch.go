@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// watchFile polls path every interval and calls reload whenever the
+// file's modification time or size changes, until done is closed. The
+// initial state of the file is recorded before watchFile returns, so
+// callers should load the file after calling watchFile to avoid missing
+// a write in between.
+func watchFile(path string, interval time.Duration, done <-chan struct{}, reload func()) {
+	last, _ := os.Stat(path)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+
+			fi, err := os.Stat(path)
+			if err != nil {
+				// The file may be missing briefly while it is being replaced.
+				// Keep serving the previous data until it shows up again.
+				if last != nil {
+					log.Printf("Stat %s failed: %s", path, err)
+				}
+				last = nil
+				continue
+			}
+
+			if last != nil && fi.ModTime().Equal(last.ModTime()) && fi.Size() == last.Size() {
+				continue
+			}
+
+			last = fi
+			reload()
+		}
+	}()
+}